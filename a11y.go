@@ -0,0 +1,101 @@
+package goboringavatars
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// Label sets the accessible name of the Avatar, written as its title, in
+// place of the name it is generated from. This keeps the hashed input, such as
+// an email address, out of the markup.
+func Label(text string) Option {
+	return option(func(c *config) error {
+		c.label = text
+		return nil
+	})
+}
+
+// Description adds a desc element describing the Avatar.
+func Description(text string) Option {
+	return option(func(c *config) error {
+		c.description = text
+		return nil
+	})
+}
+
+// Lang sets the language of the title and description, as a BCP 47 tag such as "en-GB".
+func Lang(tag string) Option {
+	return option(func(c *config) error {
+
+		valid := tag != "" && strings.Trim(tag, "-") == tag && !strings.Contains(tag, "--")
+
+		for _, r := range tag {
+			if !(r == '-' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+				valid = false
+			}
+		}
+
+		if !valid {
+			return &OptionError{"Lang", tag, ErrInvalidLang}
+		}
+
+		c.lang = tag
+
+		return nil
+	})
+}
+
+// Decorative hides the Avatar from assistive technology, for when the name is
+// already written next to it. The title and description are left out.
+func Decorative() Option {
+	return option(func(c *config) error {
+		c.decorative = true
+		return nil
+	})
+}
+
+// accessible writes the attributes of the svg element that describe it to
+// assistive technology, and returns the title and description elements.
+func (a config) accessible(svg *strings.Builder) string {
+
+	if a.decorative {
+		svg.WriteString(` aria-hidden="true"`)
+		return ""
+	}
+
+	svg.WriteString(` role="img"`)
+
+	if a.lang != "" {
+		svg.WriteString(fmt.Sprintf(` lang="%s"`, a.lang))
+	}
+
+	var (
+		elements = strings.Builder{}
+		title    = a.label
+	)
+
+	if title == "" && a.title && !a.private {
+		title = a.display
+	}
+
+	// The ids come from the text, so avatars on the same page don't clash.
+	for _, e := range []struct {
+		element, attribute, text string
+	}{
+		{"title", "aria-labelledby", title},
+		{"desc", "aria-describedby", a.description},
+	} {
+
+		if e.text == "" {
+			continue
+		}
+
+		id := a.id(fmt.Sprintf("%s_%d", e.element, hashCode(a.name+"\n"+e.text)))
+
+		svg.WriteString(fmt.Sprintf(` %s="%s"`, e.attribute, id))
+		elements.WriteString(fmt.Sprintf(`<%s id="%s">%s</%s>`, e.element, id, html.EscapeString(e.text), e.element))
+	}
+
+	return elements.String()
+}
@@ -0,0 +1,368 @@
+package goboringavatars
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// property is the value a motion animates.
+type property int
+
+const (
+	rotate property = iota
+	scale
+	scaleY
+	translateY
+	opacity
+)
+
+// keyframe is the value of a property at an offset (0 to 1) into the cycle.
+type keyframe struct {
+	offset float64
+	value  float64
+}
+
+// motion describes a looping animation applied to a group of elements.
+// Keyframes are interpolated linearly so the CSS output and any sampled
+// frame agree with each other.
+type motion struct {
+	class    string // class suffix, unique within the avatar
+	name     string // keyframes name, shared by motions with the same frames
+	property property
+	originX  float64
+	originY  float64
+	duration float64 // seconds for one cycle
+	offset   float64 // seconds into the cycle the animation starts at
+	frames   []keyframe
+}
+
+// at returns the value of the animated property t seconds after the avatar was shown.
+func (m motion) at(t float64) float64 {
+
+	phase := math.Mod((t+m.offset)/m.duration, 1)
+
+	if phase < 0 {
+		phase++
+	}
+
+	for i := 1; i < len(m.frames); i++ {
+
+		prev, next := m.frames[i-1], m.frames[i]
+
+		if phase <= next.offset {
+
+			if next.offset == prev.offset {
+				return next.value
+			}
+
+			return prev.value + (next.value-prev.value)*(phase-prev.offset)/(next.offset-prev.offset)
+		}
+
+	}
+
+	return m.frames[len(m.frames)-1].value
+}
+
+// declaration returns the CSS declaration setting the property to value.
+func (m motion) declaration(value float64) string {
+	switch m.property {
+	case rotate:
+		return "transform:rotate(" + formatNumber(value) + "deg)"
+	case scale:
+		return "transform:scale(" + formatNumber(value) + ")"
+	case scaleY:
+		return "transform:scaleY(" + formatNumber(value) + ")"
+	case translateY:
+		return "transform:translateY(" + formatNumber(value) + "px)"
+	default:
+		return "opacity:" + formatNumber(value)
+	}
+}
+
+// keyframes returns the @keyframes rule of the motion.
+func (m motion) keyframes(prefix string) string {
+
+	s := strings.Builder{}
+
+	s.WriteString(fmt.Sprintf(`@keyframes %s-%s{`, prefix, m.name))
+
+	for _, f := range m.frames {
+		s.WriteString(fmt.Sprintf(`%s%%{%s}`, formatNumber(f.offset*100), m.declaration(f.value)))
+	}
+
+	s.WriteString(`}`)
+
+	return s.String()
+}
+
+// rule returns the CSS rule that attaches the motion to its class.
+func (m motion) rule(prefix string) string {
+
+	var (
+		s     = strings.Builder{}
+		start = math.Mod(m.offset, m.duration)
+	)
+
+	if start < 0 {
+		start += m.duration
+	}
+
+	s.WriteString(fmt.Sprintf(`.%s-%s{`, prefix, m.class))
+
+	if m.property != opacity {
+		s.WriteString(fmt.Sprintf(`transform-box:view-box;transform-origin:%spx %spx;`, formatNumber(m.originX), formatNumber(m.originY)))
+	}
+
+	s.WriteString(fmt.Sprintf(`animation:%s-%s %ss linear %ss infinite}`, prefix, m.name, formatNumber(m.duration), formatNumber(-start)))
+
+	return s.String()
+}
+
+// attribute returns the attribute that holds the property at its value t seconds in.
+func (m motion) attribute(t float64) string {
+
+	v := formatNumber(m.at(t))
+	x, y := formatNumber(m.originX), formatNumber(m.originY)
+
+	switch m.property {
+	case rotate:
+		return fmt.Sprintf(`transform="rotate(%s %s %s)"`, v, x, y)
+	case scale:
+		return fmt.Sprintf(`transform="translate(%s %s) scale(%s) translate(-%s -%s)"`, x, y, v, x, y)
+	case scaleY:
+		return fmt.Sprintf(`transform="translate(%s %s) scale(1 %s) translate(-%s -%s)"`, x, y, v, x, y)
+	case translateY:
+		return fmt.Sprintf(`transform="translate(0 %s)"`, v)
+	default:
+		return fmt.Sprintf(`opacity="%s"`, v)
+	}
+}
+
+// animated opens a group that plays the motion, or holds it still at the
+// configured time when the animation is frozen. Close it with `</g>`.
+func (a config) animated(svg *strings.Builder, m motion) {
+
+	if a.frozen {
+		svg.WriteString(fmt.Sprintf(`<g %s>`, m.attribute(a.time)))
+		return
+	}
+
+	svg.WriteString(fmt.Sprintf(`<g class="%s-%s">`, a.classPrefix(), m.class))
+}
+
+// style returns a style element with the CSS for the motions, which is
+// disabled for visitors that prefer reduced motion.
+func (a config) style(motions ...motion) string {
+
+	if len(motions) == 0 || a.frozen {
+		return ""
+	}
+
+	var (
+		prefix  = a.classPrefix()
+		s       = strings.Builder{}
+		seen    = map[string]bool{}
+		classes = make([]string, 0, len(motions))
+	)
+
+	s.WriteString(`<style>`)
+
+	for _, m := range motions {
+
+		s.WriteString(m.rule(prefix))
+
+		if !seen[m.name] {
+			s.WriteString(m.keyframes(prefix))
+			seen[m.name] = true
+		}
+
+		classes = append(classes, "."+prefix+"-"+m.class)
+	}
+
+	s.WriteString(fmt.Sprintf(`@media (prefers-reduced-motion:reduce){%s{animation:none}}`, strings.Join(classes, ",")))
+
+	s.WriteString(`</style>`)
+
+	return s.String()
+}
+
+// loop returns how many seconds it takes the animation of the avatar to
+// repeat, or zero if it doesn't move.
+func (a config) loop() float64 {
+
+	var (
+		n       = a.hash()
+		motions []motion
+		longest float64
+	)
+
+	switch {
+	case !a.animate && !(a.blink && a.variant == Beam):
+		return 0
+	case a.variant == Beam:
+		// Only the eyes move, and not every expression has open ones.
+		motions = a.face(&strings.Builder{}, beamData{})
+	case a.variant == Ring:
+		motions = ringMotions(n)
+	case a.variant == Sunset:
+		motions = sunsetMotions(n)
+	case a.variant == Pixel:
+		motions = pixelMotions(n)
+	case a.variant == Marble:
+		motions = marbleMotions(n)
+	}
+
+	// Every motion of an avatar lasts a whole fraction of the longest one.
+	for _, m := range motions {
+		longest = math.Max(longest, m.duration)
+	}
+
+	return longest
+}
+
+// wave returns keyframes that swing smoothly from rest to rest+amplitude,
+// back through rest to rest-amplitude and home again.
+func wave(rest, amplitude float64) []keyframe {
+
+	const steps = 8
+
+	frames := make([]keyframe, 0, steps+1)
+
+	for i := 0; i <= steps; i++ {
+		offset := float64(i) / steps
+		frames = append(frames, keyframe{offset, math.Round((rest+amplitude*math.Sin(2*math.Pi*offset))*1000) / 1000})
+	}
+
+	return frames
+}
+
+// pulse returns keyframes that rise smoothly from rest to peak and back once per cycle.
+func pulse(rest, peak float64) []keyframe {
+
+	const steps = 8
+
+	frames := make([]keyframe, 0, steps+1)
+
+	for i := 0; i <= steps; i++ {
+		offset := float64(i) / steps
+		frames = append(frames, keyframe{offset, math.Round((rest+(peak-rest)*(1-math.Cos(2*math.Pi*offset))/2)*1000) / 1000})
+	}
+
+	return frames
+}
+
+// formatNumber formats a number for CSS, rounded to three decimals.
+func formatNumber(f float64) string {
+
+	f = math.Round(f*1000) / 1000
+
+	// Avoid writing negative zero as "-0".
+	if f == 0 {
+		f = 0
+	}
+
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// marbleMotions spins the two blobs of a marble avatar in opposite directions.
+func marbleMotions(n int) []motion {
+
+	var (
+		duration = float64(12 + n%8)
+		turn     = 360.0
+	)
+
+	if getBoolean(n, 1) {
+		turn = -turn
+	}
+
+	return []motion{
+		{class: "blob0", name: "spin0", property: rotate, originX: svgSize / 2, originY: svgSize / 2, duration: duration, frames: []keyframe{{0, 0}, {1, turn}}},
+		{class: "blob1", name: "spin1", property: rotate, originX: svgSize / 2, originY: svgSize / 2, duration: duration / 2, offset: float64(getDigit(n, 2)) / 10 * duration / 2, frames: []keyframe{{0, 0}, {1, -turn}}},
+	}
+}
+
+// ringMotions pulses each band of a ring avatar in turn, from the outside in.
+func ringMotions(n int) []motion {
+
+	var (
+		duration = float64(3 + n%3)
+		motions  = make([]motion, 0, 4)
+	)
+
+	for i := 0; i < 4; i++ {
+		motions = append(motions, motion{
+			class:    "band" + strconv.Itoa(i),
+			name:     "pulse",
+			property: scale,
+			originX:  ringSize / 2,
+			originY:  ringSize / 2,
+			duration: duration,
+			offset:   -duration * float64(i) / 8,
+			frames:   pulse(1, 1.06),
+		})
+	}
+
+	return motions
+}
+
+// sunsetMotions lets the horizon of a sunset avatar drift up and down.
+func sunsetMotions(n int) []motion {
+
+	amplitude := float64(3 + n%4)
+
+	if getBoolean(n, 1) {
+		amplitude = -amplitude
+	}
+
+	return []motion{
+		{class: "horizon", name: "drift", property: translateY, duration: float64(8 + n%5), offset: float64(getDigit(n, 2)), frames: wave(0, amplitude)},
+	}
+}
+
+// pixelMotions makes roughly a third of the cells of a pixel avatar shimmer.
+// The index of each returned motion is the cell it belongs to, and cells
+// that stay still have a zero motion.
+func pixelMotions(n int) []motion {
+
+	var (
+		duration = float64(2 + n%3)
+		motions  = make([]motion, len(pixelCells))
+	)
+
+	for i := range pixelCells {
+
+		if getDigit(n*(i+1), 1) > 2 {
+			continue
+		}
+
+		motions[i] = motion{
+			class:    "cell" + strconv.Itoa(i),
+			name:     "shimmer",
+			property: opacity,
+			duration: duration,
+			offset:   duration * float64(getDigit(n*(i+1), 2)) / 10,
+			frames:   pulse(1, 0.6),
+		}
+	}
+
+	return motions
+}
+
+// blinkMotions closes the eyes of a beam avatar once per cycle. The eyes are
+// drawn at the given x positions, 14 to 16 units down the face.
+func blinkMotions(n int, left, right float64) []motion {
+
+	var (
+		duration = float64(3 + n%4)
+		offset   = -float64(getDigit(n, 3)) / 10 * duration
+		frames   = []keyframe{{0, 1}, {0.9, 1}, {0.95, 0.1}, {1, 1}}
+	)
+
+	return []motion{
+		{class: "eye0", name: "blink", property: scaleY, originX: left + 0.75, originY: 15, duration: duration, offset: offset, frames: frames},
+		{class: "eye1", name: "blink", property: scaleY, originX: right + 0.75, originY: 15, duration: duration, offset: offset, frames: frames},
+	}
+}
@@ -0,0 +1,138 @@
+package goboringavatars
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+// Corner limits where a badge is placed.
+type Corner struct {
+	corner string
+}
+
+func (c Corner) String() string {
+	return c.corner
+}
+
+func ValidateCorner(corner Corner) bool {
+	switch corner.String() {
+	case "top-left", "top-right", "bottom-left", "bottom-right":
+		return true
+	default:
+		return false
+	}
+}
+
+var (
+	TopLeft     = Corner{"top-left"}
+	TopRight    = Corner{"top-right"}
+	BottomLeft  = Corner{"bottom-left"}
+	BottomRight = Corner{"bottom-right"}
+)
+
+// badge is a dot or counter drawn over a corner of the avatar.
+type badge struct {
+	corner  Corner
+	color   string
+	text    string
+	outline string
+}
+
+// Badge adds a dot in the color to a corner of the Avatar, such as a presence
+// indicator. With text, such as a count of notifications, the dot holds it in
+// a contrasting color and stretches into a pill when it is longer than one
+// character. Text is left out of rasterized images.
+func Badge(corner Corner, color, text string) Option {
+	return option(func(c *config) error {
+
+		if !ValidateCorner(corner) {
+			return &OptionError{"Badge", corner, ErrInvalidCorner}
+		}
+
+		if !validColor(color) {
+			return &OptionError{"Badge", color, ErrInvalidColor}
+		}
+
+		outline := ""
+		if c.badge != nil {
+			outline = c.badge.outline
+		}
+
+		c.badge = &badge{corner: corner, color: color, text: text, outline: outline}
+
+		return nil
+	})
+}
+
+// BadgeOutline draws a ring in the color around the badge, usually the color
+// of the page, so the badge stands apart from the avatar.
+func BadgeOutline(color string) Option {
+	return option(func(c *config) error {
+
+		if !validColor(color) {
+			return &OptionError{"BadgeOutline", color, ErrInvalidColor}
+		}
+
+		if c.badge == nil {
+			c.badge = &badge{corner: BottomRight}
+		}
+
+		c.badge.outline = color
+
+		return nil
+	})
+}
+
+// draw writes the badge into an svg with a viewBox of size units.
+func (b *badge) draw(svg *strings.Builder, size float64) {
+
+	// An outline without a Badge has nothing to go around.
+	if b.color == "" {
+		return
+	}
+
+	var (
+		radius = size * 0.15
+		ring   = 0.0
+		length = utf8.RuneCountInString(b.text)
+		font   = radius * 1.2
+	)
+
+	if b.outline != "" {
+		ring = size * 0.05
+	}
+
+	// The dot is kept inside the viewBox with its outline.
+	var (
+		width = radius*2 + float64(max(length-1, 0))*font*0.6
+		x     = ring
+		y     = ring
+	)
+
+	if b.corner == TopRight || b.corner == BottomRight {
+		x = size - ring - width
+	}
+
+	if b.corner == BottomLeft || b.corner == BottomRight {
+		y = size - ring - radius*2
+	}
+
+	if ring > 0 {
+		svg.WriteString(fmt.Sprintf(`<rect x="%s" y="%s" width="%s" height="%s" rx="%s" fill="%s"></rect>`, formatNumber(x-ring), formatNumber(y-ring), formatNumber(width+ring*2), formatNumber(radius*2+ring*2), formatNumber(radius+ring), b.outline))
+	}
+
+	svg.WriteString(fmt.Sprintf(`<rect x="%s" y="%s" width="%s" height="%s" rx="%s" fill="%s"></rect>`, formatNumber(x), formatNumber(y), formatNumber(width), formatNumber(radius*2), formatNumber(radius), b.color))
+
+	if length == 0 {
+		return
+	}
+
+	fill, err := getContrast(b.color)
+	if err != nil {
+		fill = "#FFFFFF"
+	}
+
+	svg.WriteString(fmt.Sprintf(`<text x="%s" y="%s" fill="%s" font-family="sans-serif" font-size="%s" font-weight="bold" text-anchor="middle" dominant-baseline="central">%s</text>`, formatNumber(x+width/2), formatNumber(y+radius), fill, formatNumber(font), html.EscapeString(b.text)))
+}
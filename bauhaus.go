@@ -17,11 +17,11 @@ type bauhausProps struct {
 	isSquare   bool
 }
 
-func generateBauhausColors(name string, colors []string) map[int]bauhausProps {
+func generateBauhausColors(hash int, colors []string) map[int]bauhausProps {
 
 	p := map[int]bauhausProps{}
 
-	n := hashCode(name)
+	n := hash
 
 	for i := 0; i < bauhausElement; i++ {
 
@@ -44,7 +44,7 @@ func (c config) bauhaus() string {
 
 	var svg strings.Builder
 
-	props := generateBauhausColors(c.name, c.colors)
+	props := generateBauhausColors(c.hash(), c.colors)
 
 	c.start(&svg, "avatar_bauhaus", svgSize)
 
@@ -60,7 +60,7 @@ func (c config) bauhaus() string {
 	svg.WriteString(fmt.Sprintf(`<circle cx="%d" cy="%d" fill="%s" r="%d" transform="translate(%.0f %.0f)"></circle>`, svgSize/2, svgSize/2, props[2].color, svgSize/5, props[2].translateX, props[2].translateY))
 	svg.WriteString(fmt.Sprintf(`<line x1="0" y1="%d" x2="%d" y2="%d" stroke-width="2" stroke="%s" transform="translate(%.0f %.0f) rotate(%.0f %d %d)"></line>`, svgSize/2, svgSize, svgSize/2, props[3].color, props[3].translateX, props[3].translateY, props[3].rotate, svgSize/2, svgSize/2))
 
-	c.end(&svg)
+	c.end(&svg, svgSize)
 
 	return svg.String()
 
@@ -26,8 +26,8 @@ type beamData struct {
 	faceTranslateY    float64
 }
 
-func generateData(name string, colors []string) (beamData, error) {
-	numFromName := hashCode(name)
+func generateData(hash int, colors []string) (beamData, error) {
+	numFromName := hash
 	wrapperColor := getRandomColor(numFromName, colors)
 	preTranslateX := getUnit(numFromName, 10, 1)
 	wrapperTranslateX := preTranslateX
@@ -75,7 +75,7 @@ func generateData(name string, colors []string) (beamData, error) {
 
 func (c config) beam() (string, error) {
 
-	data, err := generateData(c.name, c.colors)
+	data, err := generateData(c.hash(), c.colors)
 	if err != nil {
 		return "", err
 	}
@@ -100,18 +100,87 @@ func (c config) beam() (string, error) {
 
 	svg.WriteString(fmt.Sprintf(`<g transform="translate(%.0f %.0f) rotate(%.0f %d %d)">`, data.faceTranslateX, data.faceTranslateY, data.faceRotate, beamSize/2, beamSize/2))
 
-	if data.isMouthOpen {
-		svg.WriteString(fmt.Sprintf(`<path d="M15 %.0fc2 1 4 1 6 0" stroke="%s" fill="none" stroke-linecap="round"></path>`, 19+data.mouthSpread, data.faceColor))
-	} else {
-		svg.WriteString(fmt.Sprintf(`<path d="M13,%.0f a1,0.75 0 0,0 10,0" fill="%s"></path>`, 19+data.mouthSpread, data.faceColor))
-	}
-
-	svg.WriteString(fmt.Sprintf(`<rect x="%.0f" y="14" width="1.5" height="2" rx="1" stroke="none" fill="%s"></rect>`, 14-data.eyeSpread, data.faceColor))
-	svg.WriteString(fmt.Sprintf(`<rect x="%.0f" y="14" width="1.5" height="2" rx="1" stroke="none" fill="%s"></rect>`, 20+data.eyeSpread, data.faceColor))
+	motions := c.face(&svg, data)
 
 	svg.WriteString(`</g>`)
-	c.end(&svg)
+
+	c.end(&svg, beamSize, c.style(motions...))
 
 	return svg.String(), nil
 
 }
+
+// face draws the eyes and mouth of the beam avatar for the configured
+// expression, and returns the motions of any eyes that blink.
+func (c config) face(svg *strings.Builder, data beamData) []motion {
+
+	var (
+		mouthY  = 19 + data.mouthSpread
+		left    = 14 - data.eyeSpread
+		right   = 20 + data.eyeSpread
+		motions []motion
+	)
+
+	if c.blink || c.animate {
+		motions = blinkMotions(c.hash(), left, right)
+	}
+
+	switch c.expression {
+	case Happy:
+		svg.WriteString(fmt.Sprintf(`<path d="M13,%.0f a1,0.75 0 0,0 10,0" fill="%s"></path>`, mouthY, data.faceColor))
+	case Sad:
+		svg.WriteString(fmt.Sprintf(`<path d="M15 %.0fc2 -1 4 -1 6 0" stroke="%s" fill="none" stroke-linecap="round"></path>`, mouthY+1, data.faceColor))
+	case Surprised:
+		svg.WriteString(fmt.Sprintf(`<path d="M16.5 %.0fa1.5 2 0 1 0 3 0a1.5 2 0 1 0 -3 0" fill="%s"></path>`, mouthY+1, data.faceColor))
+	case Sleeping:
+		svg.WriteString(fmt.Sprintf(`<path d="M17 %.0fh2" stroke="%s" fill="none" stroke-linecap="round"></path>`, mouthY, data.faceColor))
+	case Winking:
+		svg.WriteString(fmt.Sprintf(`<path d="M15 %.0fc2 1 4 1 6 0" stroke="%s" fill="none" stroke-linecap="round"></path>`, mouthY, data.faceColor))
+	case Neutral:
+		svg.WriteString(fmt.Sprintf(`<path d="M15 %.0fh6" stroke="%s" fill="none" stroke-linecap="round"></path>`, mouthY, data.faceColor))
+	default:
+		if data.isMouthOpen {
+			svg.WriteString(fmt.Sprintf(`<path d="M15 %.0fc2 1 4 1 6 0" stroke="%s" fill="none" stroke-linecap="round"></path>`, mouthY, data.faceColor))
+		} else {
+			svg.WriteString(fmt.Sprintf(`<path d="M13,%.0f a1,0.75 0 0,0 10,0" fill="%s"></path>`, mouthY, data.faceColor))
+		}
+	}
+
+	switch c.expression {
+	case Sleeping:
+		c.closedEye(svg, left, data.faceColor)
+		c.closedEye(svg, right, data.faceColor)
+		return nil
+	case Winking:
+		c.eye(svg, motions, 0, left, 2, data.faceColor)
+		c.closedEye(svg, right, data.faceColor)
+		if motions != nil {
+			motions = motions[:1]
+		}
+	case Surprised:
+		c.eye(svg, motions, 0, left, 3, data.faceColor)
+		c.eye(svg, motions, 1, right, 3, data.faceColor)
+	default:
+		c.eye(svg, motions, 0, left, 2, data.faceColor)
+		c.eye(svg, motions, 1, right, 2, data.faceColor)
+	}
+
+	return motions
+
+}
+
+// eye draws an open eye, which blinks when the motions are set.
+func (c config) eye(svg *strings.Builder, motions []motion, index int, x, height float64, color string) {
+
+	if motions != nil {
+		c.animated(svg, motions[index])
+		defer svg.WriteString(`</g>`)
+	}
+
+	svg.WriteString(fmt.Sprintf(`<rect x="%.0f" y="%.0f" width="1.5" height="%.0f" rx="1" stroke="none" fill="%s"></rect>`, x, 16-height, height, color))
+}
+
+// closedEye draws a shut eye as a small downward curve.
+func (c config) closedEye(svg *strings.Builder, x float64, color string) {
+	svg.WriteString(fmt.Sprintf(`<path d="M%.0f 15q0.75 1 1.5 0" stroke="%s" stroke-width="0.75" fill="none" stroke-linecap="round"></path>`, x, color))
+}
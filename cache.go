@@ -0,0 +1,173 @@
+package goboringavatars
+
+import (
+	"container/list"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Cache stores rendered avatars by their Fingerprint. Caches are used from many
+// goroutines at once.
+type Cache interface {
+	Get(key string) (svg string, ok bool)
+	Set(key, svg string)
+}
+
+// MemoryCache keeps the most recently used avatars in memory, up to a number
+// of avatars and a total size in bytes.
+type MemoryCache struct {
+	mu       sync.Mutex
+	entries  int
+	bytes    int
+	size     int
+	recent   *list.List
+	elements map[string]*list.Element
+}
+
+type memoryEntry struct {
+	key string
+	svg string
+}
+
+// NewMemoryCache returns a MemoryCache holding up to entries avatars and bytes
+// of markup, dropping the least recently used ones first. A limit of zero or
+// less leaves that dimension unbounded.
+func NewMemoryCache(entries, bytes int) *MemoryCache {
+	return &MemoryCache{
+		entries:  entries,
+		bytes:    bytes,
+		recent:   list.New(),
+		elements: map[string]*list.Element{},
+	}
+}
+
+// Get returns the avatar stored under the key.
+func (m *MemoryCache) Get(key string) (string, bool) {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.elements[key]
+	if !ok {
+		return "", false
+	}
+
+	m.recent.MoveToFront(e)
+
+	return e.Value.(*memoryEntry).svg, true
+}
+
+// Set stores the avatar under the key. Avatars larger than the whole cache are not stored.
+func (m *MemoryCache) Set(key, svg string) {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.bytes > 0 && len(svg) > m.bytes {
+		return
+	}
+
+	if e, ok := m.elements[key]; ok {
+
+		entry := e.Value.(*memoryEntry)
+
+		m.size += len(svg) - len(entry.svg)
+		entry.svg = svg
+
+		m.recent.MoveToFront(e)
+
+	} else {
+
+		m.elements[key] = m.recent.PushFront(&memoryEntry{key: key, svg: svg})
+		m.size += len(svg)
+	}
+
+	for (m.entries > 0 && m.recent.Len() > m.entries) || (m.bytes > 0 && m.size > m.bytes) {
+
+		entry := m.recent.Remove(m.recent.Back()).(*memoryEntry)
+
+		delete(m.elements, entry.key)
+		m.size -= len(entry.svg)
+	}
+}
+
+// Len returns the number of avatars in the cache.
+func (m *MemoryCache) Len() int {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.recent.Len()
+}
+
+// DiskCache keeps avatars as files in a directory, which survive restarts and
+// can be shared between processes.
+type DiskCache struct {
+	dir string
+}
+
+// NewDiskCache returns a DiskCache storing avatars under dir, which is
+// created when the first avatar is stored.
+func NewDiskCache(dir string) *DiskCache {
+	return &DiskCache{dir: dir}
+}
+
+// path returns the file of the key, spread over directories by its last
+// characters. Keys that aren't safe file names have no file.
+func (d *DiskCache) path(key string) (string, bool) {
+
+	if len(key) < 3 || strings.Trim(key, "-0123456789abcdefghijklmnopqrstuvwxyz") != "" {
+		return "", false
+	}
+
+	return filepath.Join(d.dir, key[len(key)-2:], key+".svg"), true
+}
+
+// Get returns the avatar stored under the key.
+func (d *DiskCache) Get(key string) (string, bool) {
+
+	file, ok := d.path(key)
+	if !ok {
+		return "", false
+	}
+
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return "", false
+	}
+
+	return string(b), true
+}
+
+// Set stores the avatar under the key. The file is written in full before it
+// replaces any other, so readers never see part of an avatar. Failures leave
+// the avatar out of the cache.
+func (d *DiskCache) Set(key, svg string) {
+
+	file, ok := d.path(key)
+	if !ok {
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(file), ".tmp-*")
+	if err != nil {
+		return
+	}
+
+	_, err = tmp.WriteString(svg)
+
+	if err = errors.Join(err, tmp.Close()); err == nil {
+		err = os.Rename(tmp.Name(), file)
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+}
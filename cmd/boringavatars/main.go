@@ -0,0 +1,176 @@
+// Command boringavatars previews avatars from the command line.
+//
+// Usage:
+//
+//	boringavatars candidates [flags] name > candidates.html
+//	boringavatars gallery [flags] name... > gallery.html
+//
+// The candidates command writes an HTML page with the avatars of the first
+// seeds of the name, labelled with their seed, to pick one to keep.
+//
+// The gallery command writes an HTML page, or an svg with -svg, with a table
+// of the names by the variants for each -palette, such as
+// -palette "Sunrise=#264653,#2a9d8f,#e9c46a,#f4a261,#e76f51".
+package main
+
+import (
+	"flag"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"strings"
+
+	avatars "github.com/hcarriz/go-boring-avatars"
+)
+
+func main() {
+
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+
+	switch os.Args[1] {
+	case "candidates":
+		err = candidates(os.Stdout, os.Args[2:])
+	case "gallery":
+		err = gallery(os.Stdout, os.Args[2:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "boringavatars:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: boringavatars candidates [flags] name")
+	fmt.Fprintln(os.Stderr, "       boringavatars gallery [flags] name...")
+}
+
+// style adds the flags that set the options of the avatars.
+func style(flags *flag.FlagSet) *avatars.Config {
+
+	c := &avatars.Config{}
+
+	flags.StringVar(&c.Variant, "variant", "", "variant of the avatars")
+	flags.StringVar(&c.Size, "size", "80px", "size of the avatars, with a CSS unit")
+	flags.BoolVar(&c.Square, "square", false, "draw square avatars")
+	flags.Func("colors", "the five colors of the palette, separated by commas", func(value string) error {
+		c.Colors = strings.Split(value, ",")
+		return nil
+	})
+
+	return c
+}
+
+// candidates writes a page of the first seeds of a name.
+func candidates(w io.Writer, args []string) error {
+
+	flags := flag.NewFlagSet("candidates", flag.ContinueOnError)
+
+	var (
+		n = flags.Int("n", 12, "number of candidates")
+		c = style(flags)
+	)
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("candidates takes one name, not %d", flags.NArg())
+	}
+
+	opts, err := c.Options()
+	if err != nil {
+		return err
+	}
+
+	name := flags.Arg(0)
+
+	list, err := avatars.Candidates(name, *n, opts...)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\"><title>%s</title></head>\n", html.EscapeString(name))
+	fmt.Fprintln(w, `<body style="display:flex;flex-wrap:wrap;gap:16px;font-family:sans-serif">`)
+
+	for seed, svg := range list {
+		fmt.Fprintf(w, "<figure style=\"margin:0;text-align:center\">%s<figcaption>seed %d</figcaption></figure>\n", svg, seed)
+	}
+
+	_, err = fmt.Fprintln(w, "</body></html>")
+
+	return err
+}
+
+// gallery writes a page of the names by the variants for each palette.
+func gallery(w io.Writer, args []string) error {
+
+	flags := flag.NewFlagSet("gallery", flag.ContinueOnError)
+
+	var (
+		variants []avatars.Name
+		palettes []avatars.Palette
+		asSVG    = flags.Bool("svg", false, "write a single svg instead of an HTML page")
+		c        = style(flags)
+	)
+
+	flags.Func("variants", "the variants to show, separated by commas (default all)", func(value string) error {
+
+		for _, name := range strings.Split(value, ",") {
+
+			variant, err := avatars.ParseName(name)
+			if err != nil {
+				return err
+			}
+
+			variants = append(variants, variant)
+		}
+
+		return nil
+	})
+
+	flags.Func("palette", "a palette to show, as name=five,colors,... (repeatable)", func(value string) error {
+
+		name, colors, ok := strings.Cut(value, "=")
+		if !ok {
+			name, colors = "", value
+		}
+
+		palettes = append(palettes, avatars.Palette{Name: name, Colors: strings.Split(colors, ",")})
+
+		return nil
+	})
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	opts, err := c.Options()
+	if err != nil {
+		return err
+	}
+
+	render := avatars.Gallery
+	if *asSVG {
+		render = avatars.GallerySVG
+	}
+
+	out, err := render(flags.Args(), variants, palettes, opts...)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(w, out)
+
+	return err
+}
@@ -0,0 +1,276 @@
+package goboringavatars
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Config holds options as plain values, so they can be read from JSON or YAML
+// files, URL queries and environment variables. The zero value adds no options.
+type Config struct {
+	Size       string   `json:"size,omitempty" yaml:"size,omitempty"`             // a number with an optional unit, such as "40" or "2.5rem"
+	Width      string   `json:"width,omitempty" yaml:"width,omitempty"`           // like Size, for the width alone
+	Height     string   `json:"height,omitempty" yaml:"height,omitempty"`         // like Size, for the height alone
+	Responsive bool     `json:"responsive,omitempty" yaml:"responsive,omitempty"` // see Responsive, which leaves out the sizes that aren't set
+	Square     bool     `json:"square,omitempty" yaml:"square,omitempty"`         // see Square
+	Title      bool     `json:"title,omitempty" yaml:"title,omitempty"`           // see Title
+	Seed       int      `json:"seed,omitempty" yaml:"seed,omitempty"`             // see Seed
+	Variant    string   `json:"variant,omitempty" yaml:"variant,omitempty"`       // a variant name, as read by ParseName
+	Colors     []string `json:"colors,omitempty" yaml:"colors,omitempty"`         // none, or the five of Colors
+	Classes    []string `json:"classes,omitempty" yaml:"classes,omitempty"`       // see Classes
+}
+
+// Options returns the options the Config describes. Each one is checked as
+// it is made, so errors name the field that is wrong.
+func (c Config) Options() ([]Option, error) {
+
+	var (
+		opts    = []Option{}
+		scratch = config{}
+		add     = func(field string, opt Option) error {
+
+			if err := opt.apply(&scratch); err != nil {
+				return fmt.Errorf("%s: %w", field, err)
+			}
+
+			opts = append(opts, opt)
+
+			return nil
+		}
+	)
+
+	if c.Responsive {
+		if err := add("responsive", Responsive()); err != nil {
+			return nil, err
+		}
+	}
+
+	sizes := []struct {
+		field  string
+		value  string
+		option func(float64, string) Option
+	}{
+		{"size", c.Size, Size},
+		{"width", c.Width, Width},
+		{"height", c.Height, Height},
+	}
+
+	for _, s := range sizes {
+
+		if s.value == "" {
+			continue
+		}
+
+		size, unit, err := parseSize(s.value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", s.field, err)
+		}
+
+		if err := add(s.field, s.option(size, unit)); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.Square {
+		if err := add("square", Square()); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.Title {
+		if err := add("title", Title()); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.Seed != 0 {
+		if err := add("seed", Seed(c.Seed)); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.Variant != "" {
+
+		variant, err := ParseName(c.Variant)
+		if err != nil {
+			return nil, fmt.Errorf("variant: %w", err)
+		}
+
+		if err := add("variant", Variant(variant)); err != nil {
+			return nil, err
+		}
+	}
+
+	switch len(c.Colors) {
+	case 0:
+	case 5:
+		if err := add("colors", Colors(c.Colors[0], c.Colors[1], c.Colors[2], c.Colors[3], c.Colors[4])); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("colors: %w, not %d", ErrInvalidColors, len(c.Colors))
+	}
+
+	if len(c.Classes) > 0 {
+		if err := add("classes", Classes(c.Classes...)); err != nil {
+			return nil, err
+		}
+	}
+
+	return opts, nil
+}
+
+// ParseQuery reads a Config from URL query values, such as
+// ?variant=beam&size=64px&square&colors=264653,2a9d8f,e9c46a,f4a261,e76f51.
+// Lists are separated by commas or repeated, flags without a value are set,
+// and six digit hex colors may leave out the # that a URL would need escaped.
+func ParseQuery(query url.Values) (Config, error) {
+
+	c := Config{
+		Size:    query.Get("size"),
+		Width:   query.Get("width"),
+		Height:  query.Get("height"),
+		Variant: query.Get("variant"),
+		Colors:  splitList(query["colors"]),
+		Classes: splitList(query["classes"]),
+	}
+
+	for i, color := range c.Colors {
+		if len(color) == 6 && strings.Trim(color, "0123456789abcdefABCDEF") == "" {
+			c.Colors[i] = "#" + color
+		}
+	}
+
+	var err error
+
+	if c.Seed, err = parseSeed(query.Get("seed")); err != nil {
+		return Config{}, fmt.Errorf("seed: %w", err)
+	}
+
+	if c.Responsive, err = parseFlag(query, "responsive"); err != nil {
+		return Config{}, err
+	}
+
+	if c.Square, err = parseFlag(query, "square"); err != nil {
+		return Config{}, err
+	}
+
+	if c.Title, err = parseFlag(query, "title"); err != nil {
+		return Config{}, err
+	}
+
+	return c, nil
+}
+
+// ParseEnv reads a Config from the environment variables starting with the
+// prefix, such as AVATAR_SIZE, AVATAR_WIDTH, AVATAR_HEIGHT, AVATAR_RESPONSIVE,
+// AVATAR_SQUARE, AVATAR_TITLE, AVATAR_SEED, AVATAR_VARIANT, AVATAR_COLORS and
+// AVATAR_CLASSES for the prefix "AVATAR_". Lists are separated by commas.
+func ParseEnv(prefix string) (Config, error) {
+
+	c := Config{
+		Size:    os.Getenv(prefix + "SIZE"),
+		Width:   os.Getenv(prefix + "WIDTH"),
+		Height:  os.Getenv(prefix + "HEIGHT"),
+		Variant: os.Getenv(prefix + "VARIANT"),
+		Colors:  splitList([]string{os.Getenv(prefix + "COLORS")}),
+		Classes: splitList([]string{os.Getenv(prefix + "CLASSES")}),
+	}
+
+	seed, err := parseSeed(os.Getenv(prefix + "SEED"))
+	if err != nil {
+		return Config{}, fmt.Errorf("%s: %w", prefix+"SEED", err)
+	}
+
+	c.Seed = seed
+
+	flags := []struct {
+		key string
+		set *bool
+	}{
+		{"RESPONSIVE", &c.Responsive},
+		{"SQUARE", &c.Square},
+		{"TITLE", &c.Title},
+	}
+
+	for _, flag := range flags {
+
+		value := os.Getenv(prefix + flag.key)
+		if value == "" {
+			continue
+		}
+
+		set, err := strconv.ParseBool(value)
+		if err != nil {
+			return Config{}, fmt.Errorf("%s: %w", prefix+flag.key, err)
+		}
+
+		*flag.set = set
+	}
+
+	return c, nil
+}
+
+// parseSize splits a size such as "2.5rem" into its number and unit.
+func parseSize(size string) (float64, string, error) {
+
+	size = strings.TrimSpace(size)
+
+	unit := strings.TrimLeft(size, "0123456789.+-")
+
+	number, err := strconv.ParseFloat(strings.TrimSuffix(size, unit), 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("%w: %q", ErrInvalidSize, size)
+	}
+
+	return number, unit, nil
+}
+
+// parseSeed reads a seed, where an empty value is seed 0.
+func parseSeed(value string) (int, error) {
+
+	if value == "" {
+		return 0, nil
+	}
+
+	return strconv.Atoi(value)
+}
+
+// parseFlag reads a boolean query value, where a key without a value is true.
+func parseFlag(query url.Values, key string) (bool, error) {
+
+	if !query.Has(key) {
+		return false, nil
+	}
+
+	value := query.Get(key)
+	if value == "" {
+		return true, nil
+	}
+
+	set, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", key, err)
+	}
+
+	return set, nil
+}
+
+// splitList returns the non-empty items of comma separated lists.
+func splitList(lists []string) []string {
+
+	var items []string
+
+	for _, list := range lists {
+		for _, item := range strings.Split(list, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+
+	return items
+}
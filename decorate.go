@@ -0,0 +1,187 @@
+package goboringavatars
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Sizes of the drop shadow, as fractions of the width of the avatar.
+const (
+	shadowOffset = 0.02
+	shadowBlur   = 0.03
+	shadowMargin = 0.08
+)
+
+// decoration is a border or ring drawn around the avatar. Sizes are
+// percentages of the width of the avatar.
+type decoration struct {
+	color string // empty for a gradient between two colors of the palette
+	width float64
+	gap   float64
+}
+
+// Border draws a line in the color along the inside edge of the Avatar. The
+// width is a percentage of the width of the Avatar, up to 50.
+func Border(color string, width float64) Option {
+	return option(func(c *config) error {
+
+		if !validColor(color) {
+			return &OptionError{"Border", color, ErrInvalidColor}
+		}
+
+		if width <= 0 || width > 50 {
+			return &OptionError{"Border", width, ErrInvalidWidth}
+		}
+
+		c.border = &decoration{color: color, width: width}
+
+		return nil
+	})
+}
+
+// GradientBorder draws a border in a gradient between two colors of the
+// palette, picked from the name.
+func GradientBorder(width float64) Option {
+	return option(func(c *config) error {
+
+		if width <= 0 || width > 50 {
+			return &OptionError{"GradientBorder", width, ErrInvalidWidth}
+		}
+
+		c.border = &decoration{width: width}
+
+		return nil
+	})
+}
+
+// StoryRing draws a ring around the Avatar, with a gap between the two, in a
+// gradient between two colors of the palette picked from the name. The width
+// and gap are percentages of the width of the Avatar, which shrinks to make
+// room for them.
+func StoryRing(width, gap float64) Option {
+	return option(func(c *config) error {
+
+		if width <= 0 || gap < 0 || width+gap >= 50 {
+			return &OptionError{"StoryRing", fmt.Sprintf("%v, %v", width, gap), ErrInvalidWidth}
+		}
+
+		c.storyRing = &decoration{width: width, gap: gap}
+
+		return nil
+	})
+}
+
+// Shadow casts a soft shadow below the Avatar, which shrinks to make room for it.
+func Shadow() Option {
+	return option(func(c *config) error {
+		c.shadow = true
+		return nil
+	})
+}
+
+// inset returns how far the avatar is pulled in from the edges of an svg of
+// size units, to make room for its decorations.
+func (a config) inset(size float64) float64 {
+
+	inset := 0.0
+
+	if a.storyRing != nil {
+		inset += (a.storyRing.width + a.storyRing.gap) * size / 100
+	}
+
+	if a.shadow {
+		inset += shadowMargin * size
+	}
+
+	return inset
+}
+
+// gradient returns the id of a gradient between two colors of the palette,
+// picked from the name, and its definition.
+func (a config) gradient(kind string, size int) (string, string) {
+
+	var (
+		n  = a.hash()
+		id = a.id(fmt.Sprintf("%s_gradient_%d_%d", kind, n, size))
+	)
+
+	return id, fmt.Sprintf(`<linearGradient id="%s" x1="0" y1="0" x2="%d" y2="%d" gradientUnits="userSpaceOnUse"><stop stop-color="%s" /><stop offset="1" stop-color="%s" /></linearGradient>`, id, size, size, getRandomColor(n, a.colors), getRandomColor(n+2, a.colors))
+}
+
+// decorate opens the groups that cast the shadow and shrink the avatar.
+func (a config) decorate(svg *strings.Builder, size int) {
+
+	if a.shadow {
+		svg.WriteString(fmt.Sprintf(`<g filter="url(#%s)">`, a.id(fmt.Sprintf("shadow_%d", size))))
+	}
+
+	if inset := a.inset(float64(size)); inset > 0 {
+		svg.WriteString(fmt.Sprintf(`<g transform="translate(%s %s) scale(%s)">`, formatNumber(inset), formatNumber(inset), formatNumber((float64(size)-inset*2)/float64(size))))
+	}
+}
+
+// drawBorder draws the border, inside the mask so only its inner half shows, and
+// returns the definitions it needs.
+func (a config) drawBorder(svg *strings.Builder, size int) []string {
+
+	if a.border == nil {
+		return nil
+	}
+
+	var (
+		stroke = a.border.color
+		defs   []string
+	)
+
+	if stroke == "" {
+
+		id, def := a.gradient("border", size)
+
+		stroke = "url(#" + id + ")"
+		defs = append(defs, def)
+	}
+
+	svg.WriteString(a.outline(0, 0, float64(size), brush{fill: "none", stroke: stroke, width: a.border.width * float64(size) / 50}))
+
+	return defs
+}
+
+// undecorate closes the groups opened by decorate, draws the story ring and
+// returns the definitions they need.
+func (a config) undecorate(svg *strings.Builder, size int) []string {
+
+	var (
+		s    = float64(size)
+		defs []string
+	)
+
+	if a.inset(s) > 0 {
+		svg.WriteString(`</g>`)
+	}
+
+	if a.storyRing != nil {
+
+		var (
+			id, def = a.gradient("ring", size)
+			margin  = 0.0
+			width   = a.storyRing.width * s / 100
+		)
+
+		if a.shadow {
+			margin = shadowMargin * s
+		}
+
+		svg.WriteString(a.outline(margin+width/2, margin+width/2, s-margin*2-width, brush{fill: "none", stroke: "url(#" + id + ")", width: width}))
+
+		defs = append(defs, def)
+	}
+
+	if a.shadow {
+
+		svg.WriteString(`</g>`)
+
+		defs = append(defs, fmt.Sprintf(`<filter id="%s" x="-50%%" y="-50%%" width="200%%" height="200%%" color-interpolation-filters="sRGB"><feDropShadow dx="0" dy="%s" stdDeviation="%s" flood-color="#000000" flood-opacity="0.35" /></filter>`, a.id(fmt.Sprintf("shadow_%d", size)), formatNumber(shadowOffset*s), formatNumber(shadowBlur*s)))
+	}
+
+	return defs
+}
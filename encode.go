@@ -0,0 +1,364 @@
+package goboringavatars
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"io"
+	"math"
+	"sort"
+)
+
+// framesPerSecond is the frame rate of animated images.
+const framesPerSecond = 20
+
+// frames renders one loop of the animation of the avatar as square images of
+// px pixels. Avatars that don't move have a single frame.
+func (c config) frames(px int) ([]*image.RGBA, error) {
+
+	if px <= 0 {
+		return nil, ErrInvalidPixels
+	}
+
+	c.animate = true
+	c.frozen = true
+
+	var (
+		loop  = c.loop()
+		count = max(int(math.Round(loop*framesPerSecond)), 1)
+		list  = make([]*image.RGBA, 0, count)
+	)
+
+	for i := 0; i < count; i++ {
+
+		c.time = float64(i) / framesPerSecond
+
+		svg, err := c.render()
+		if err != nil {
+			return nil, err
+		}
+
+		img, err := rasterize(svg, px)
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, img)
+	}
+
+	return list, nil
+}
+
+// EncodeGIF writes the avatar for the name to w as an animated GIF of px by px
+// pixels that loops forever. Animate is implied, and the motion is the same as
+// the SVG output. Pixels outside of the mask are transparent.
+func EncodeGIF(w io.Writer, name string, px int, opts ...Option) error {
+
+	c, err := build(name, opts...)
+	if err != nil {
+		return err
+	}
+
+	frames, err := c.frames(px)
+	if err != nil {
+		return err
+	}
+
+	var (
+		palette = quantize(frames, 255)
+		lookup  = map[color.RGBA]uint8{}
+		out     = gif.GIF{LoopCount: 0}
+	)
+
+	for _, frame := range frames {
+
+		paletted := image.NewPaletted(frame.Bounds(), palette)
+
+		for i := 0; i < len(frame.Pix); i += 4 {
+
+			p := color.RGBA{frame.Pix[i], frame.Pix[i+1], frame.Pix[i+2], frame.Pix[i+3]}
+
+			// GIF transparency is all or nothing, index 0 is transparent.
+			if p.A < 128 {
+				continue
+			}
+
+			index, ok := lookup[p]
+			if !ok {
+				index = uint8(palette.Index(straight(p)))
+				lookup[p] = index
+			}
+
+			paletted.Pix[i/4] = index
+		}
+
+		out.Image = append(out.Image, paletted)
+		out.Delay = append(out.Delay, 100/framesPerSecond)
+		out.Disposal = append(out.Disposal, gif.DisposalBackground)
+	}
+
+	return gif.EncodeAll(w, &out)
+}
+
+// straight returns the opaque color of a premultiplied pixel.
+func straight(p color.RGBA) color.RGBA {
+
+	if p.A == 0 || p.A == 255 {
+		return color.RGBA{p.R, p.G, p.B, 255}
+	}
+
+	return color.RGBA{
+		uint8(min(int(p.R)*255/int(p.A), 255)),
+		uint8(min(int(p.G)*255/int(p.A), 255)),
+		uint8(min(int(p.B)*255/int(p.A), 255)),
+		255,
+	}
+}
+
+// quantize picks up to n colors representing the frames with a median cut,
+// after a transparent color at index 0.
+func quantize(frames []*image.RGBA, n int) color.Palette {
+
+	counts := map[color.RGBA]int{}
+
+	for _, frame := range frames {
+		for i := 0; i < len(frame.Pix); i += 4 {
+			if frame.Pix[i+3] >= 128 {
+				counts[straight(color.RGBA{frame.Pix[i], frame.Pix[i+1], frame.Pix[i+2], frame.Pix[i+3]})]++
+			}
+		}
+	}
+
+	type entry struct {
+		color color.RGBA
+		count int
+	}
+
+	all := make([]entry, 0, len(counts))
+	for c, count := range counts {
+		all = append(all, entry{c, count})
+	}
+
+	// Sort first so the palette doesn't depend on map order.
+	sort.Slice(all, func(i, j int) bool {
+		a, b := all[i].color, all[j].color
+		if a.R != b.R {
+			return a.R < b.R
+		}
+		if a.G != b.G {
+			return a.G < b.G
+		}
+		return a.B < b.B
+	})
+
+	channel := func(c color.RGBA, i int) uint8 {
+		return [3]uint8{c.R, c.G, c.B}[i]
+	}
+
+	// spread returns the channel with the widest range in the box, and that range.
+	spread := func(box []entry) (int, int) {
+
+		best, width := 0, -1
+
+		for i := 0; i < 3; i++ {
+
+			lo, hi := uint8(255), uint8(0)
+
+			for _, e := range box {
+				lo, hi = min(lo, channel(e.color, i)), max(hi, channel(e.color, i))
+			}
+
+			if int(hi)-int(lo) > width {
+				best, width = i, int(hi)-int(lo)
+			}
+		}
+
+		return best, width
+	}
+
+	boxes := [][]entry{all}
+
+	for len(boxes) < n {
+
+		// Split the box with the widest spread that holds more than one color.
+		target, widest := -1, 0
+
+		for i, box := range boxes {
+			if _, width := spread(box); len(box) > 1 && width > widest {
+				target, widest = i, width
+			}
+		}
+
+		if target < 0 {
+			break
+		}
+
+		box := boxes[target]
+		ch, _ := spread(box)
+
+		sort.SliceStable(box, func(i, j int) bool { return channel(box[i].color, ch) < channel(box[j].color, ch) })
+
+		// Cut where half of the pixels fall on either side.
+		var total, seen int
+		for _, e := range box {
+			total += e.count
+		}
+
+		cut := 1
+		for i, e := range box[:len(box)-1] {
+			seen += e.count
+			if seen*2 >= total {
+				cut = i + 1
+				break
+			}
+		}
+
+		boxes = append(boxes[:target], append([][]entry{box[:cut], box[cut:]}, boxes[target+1:]...)...)
+	}
+
+	palette := color.Palette{color.RGBA{}}
+
+	for _, box := range boxes {
+
+		var r, g, b, total int
+
+		for _, e := range box {
+			r += int(e.color.R) * e.count
+			g += int(e.color.G) * e.count
+			b += int(e.color.B) * e.count
+			total += e.count
+		}
+
+		if total > 0 {
+			palette = append(palette, color.RGBA{uint8(r / total), uint8(g / total), uint8(b / total), 255})
+		}
+	}
+
+	return palette
+}
+
+// translucent keeps the PNG encoder from dropping the alpha channel of
+// opaque frames, so every frame of an APNG has the same color type.
+type translucent struct {
+	*image.RGBA
+}
+
+func (translucent) Opaque() bool {
+	return false
+}
+
+// EncodeAPNG writes the avatar for the name to w as an animated PNG of px by
+// px pixels that loops forever. Animate is implied, and the motion is the same
+// as the SVG output. Viewers without APNG support show the first frame.
+func EncodeAPNG(w io.Writer, name string, px int, opts ...Option) error {
+
+	c, err := build(name, opts...)
+	if err != nil {
+		return err
+	}
+
+	frames, err := c.frames(px)
+	if err != nil {
+		return err
+	}
+
+	var (
+		out      = bytes.NewBuffer([]byte("\x89PNG\r\n\x1a\n"))
+		sequence uint32
+	)
+
+	for i, frame := range frames {
+
+		encoded := bytes.NewBuffer(nil)
+
+		if err := png.Encode(encoded, translucent{frame}); err != nil {
+			return err
+		}
+
+		header, data, err := pngChunks(encoded.Bytes())
+		if err != nil {
+			return err
+		}
+
+		if i == 0 {
+			writeChunk(out, "IHDR", header)
+			writeChunk(out, "acTL", binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(nil, uint32(len(frames))), 0))
+		}
+
+		control := binary.BigEndian.AppendUint32(nil, sequence)
+		control = binary.BigEndian.AppendUint32(control, uint32(px))
+		control = binary.BigEndian.AppendUint32(control, uint32(px))
+		control = binary.BigEndian.AppendUint32(control, 0) // x offset
+		control = binary.BigEndian.AppendUint32(control, 0) // y offset
+		control = binary.BigEndian.AppendUint16(control, 1)
+		control = binary.BigEndian.AppendUint16(control, framesPerSecond)
+		control = append(control, 1, 0) // clear to transparent, replace the canvas
+
+		writeChunk(out, "fcTL", control)
+		sequence++
+
+		if i == 0 {
+			writeChunk(out, "IDAT", data)
+			continue
+		}
+
+		writeChunk(out, "fdAT", append(binary.BigEndian.AppendUint32(nil, sequence), data...))
+		sequence++
+	}
+
+	writeChunk(out, "IEND", nil)
+
+	_, err = w.Write(out.Bytes())
+
+	return err
+}
+
+// pngChunks returns the header and the joined image data of an encoded PNG.
+func pngChunks(b []byte) (header, data []byte, err error) {
+
+	b = b[8:]
+
+	for len(b) >= 12 {
+
+		var (
+			length = binary.BigEndian.Uint32(b)
+			kind   = string(b[4:8])
+		)
+
+		if uint32(len(b)) < 12+length {
+			break
+		}
+
+		switch kind {
+		case "IHDR":
+			header = b[8 : 8+length]
+		case "IDAT":
+			data = append(data, b[8:8+length]...)
+		}
+
+		b = b[12+length:]
+	}
+
+	if header == nil || data == nil {
+		return nil, nil, io.ErrUnexpectedEOF
+	}
+
+	return header, data, nil
+}
+
+// writeChunk writes a PNG chunk with its length and checksum.
+func writeChunk(w *bytes.Buffer, kind string, data []byte) {
+
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(kind))
+	crc.Write(data)
+
+	w.Write(binary.BigEndian.AppendUint32(nil, uint32(len(data))))
+	w.WriteString(kind)
+	w.Write(data)
+	w.Write(binary.BigEndian.AppendUint32(nil, crc.Sum32()))
+}
@@ -0,0 +1,68 @@
+package goboringavatars
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// OptionError reports an option that was given a value it can't use. It wraps
+// one of the Err values, so both errors.As and errors.Is find it in the error
+// returned by New.
+type OptionError struct {
+	Option string // the name of the option, such as "Size"
+	Value  any    // the value that failed
+	Err    error
+}
+
+func (e *OptionError) Error() string {
+
+	value := fmt.Sprint(e.Value)
+
+	switch v := e.Value.(type) {
+	case string:
+		value = strconv.Quote(v)
+	case fmt.Stringer:
+		value = strconv.Quote(v.String())
+	}
+
+	return fmt.Sprintf("%s(%s): %v", e.Option, value, e.Err)
+}
+
+func (e *OptionError) Unwrap() error {
+	return e.Err
+}
+
+// validColor reports whether the color can be drawn, by the svg and by the
+// images made from it.
+func validColor(color string) bool {
+	_, _, _, _, ok := parseCSSColor(color)
+	return ok
+}
+
+// validUnit reports whether the unit is a CSS length unit, or empty for user units.
+func validUnit(unit string) bool {
+	switch strings.ToLower(unit) {
+	case "", "px", "em", "rem", "ex", "ch", "%", "vw", "vh", "vmin", "vmax", "cm", "mm", "q", "in", "pt", "pc":
+		return true
+	default:
+		return false
+	}
+}
+
+// validClass reports whether the class is a single class name.
+func validClass(class string) bool {
+
+	if class == "" {
+		return false
+	}
+
+	for _, r := range class {
+		if unicode.IsSpace(r) || unicode.IsControl(r) || strings.ContainsRune(`"'<>`, r) {
+			return false
+		}
+	}
+
+	return true
+}
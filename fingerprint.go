@@ -0,0 +1,132 @@
+package goboringavatars
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+// fingerprintVersion changes whenever the same options start drawing a
+// different avatar, so fingerprints made by older releases no longer match.
+const fingerprintVersion = "v2"
+
+// Fingerprint returns a digest of the avatar the name and options produce.
+// Option lists that draw the same avatar have the same fingerprint, so it
+// suits ETags, CDN keys and finding duplicates. The digest starts with its
+// version, such as "v2-", and stays the same between runs and machines.
+func Fingerprint(name string, opts ...Option) (string, error) {
+
+	c, err := build(name, opts...)
+	if err != nil {
+		return "", err
+	}
+
+	return c.fingerprint(), nil
+}
+
+// unfingerprinted are the fields of config left out of the fingerprint:
+// the normalizers are already applied to the name, the candidates are
+// resolved into the variant, and the rest are only set while rendering.
+var unfingerprinted = []string{"normalizers", "candidates", "frozen", "time", "prefix", "viewport"}
+
+// fingerprint returns a hash of everything that changes how the avatar is drawn.
+func (c config) fingerprint() string {
+
+	sum := sha256.Sum256([]byte(c.canonical()))
+
+	return fingerprintVersion + "-" + hex.EncodeToString(sum[:])
+}
+
+// canonical writes the fields that change how the avatar is drawn, a line for
+// each in a fixed order. Adding a field here changes every fingerprint, so it
+// comes with a new fingerprintVersion.
+func (c config) canonical() string {
+
+	// Options the avatar ignores are reset, so adding them keeps the
+	// fingerprint. Only Beam has a face, shut eyes don't blink, and the
+	// Label, Private and Decorative options all hide the name from the title.
+	if c.variant != Beam {
+		c.expression = Derived
+	}
+
+	if c.variant != Beam || c.expression == Sleeping {
+		c.blink = false
+	}
+
+	if c.decorative || c.label != "" || c.private {
+		c.title = false
+	}
+
+	// The name as it was given only shows in the title, so names that
+	// normalize the same draw the same avatar without one.
+	if !c.title {
+		c.display = ""
+	}
+
+	var (
+		b     = strings.Builder{}
+		field = func(key string, values ...string) {
+			b.WriteString(key + "=" + strings.Join(values, ",") + "\n")
+		}
+		text   = strconv.Quote
+		number = func(n float64) string { return strconv.FormatFloat(n, 'g', -1, 64) }
+		flag   = strconv.FormatBool
+		list   = func(items []string) []string {
+			quoted := make([]string, 0, len(items))
+			for _, item := range items {
+				quoted = append(quoted, text(item))
+			}
+			return quoted
+		}
+		decoration = func(key string, d *decoration) {
+			if d == nil {
+				field(key)
+				return
+			}
+			field(key, text(d.color), number(d.width), number(d.gap))
+		}
+	)
+
+	b.WriteString(fingerprintVersion + "\n")
+
+	field("width", text(c.width))
+	field("height", text(c.height))
+	field("square", flag(c.square))
+	field("shape", text(c.shape.mask), number(c.shape.radius), text(c.shape.path))
+	field("title", flag(c.title))
+	field("label", text(c.label))
+	field("description", text(c.description))
+	field("lang", text(c.lang))
+	field("decorative", flag(c.decorative))
+	field("private", flag(c.private))
+	field("minify", flag(c.minify))
+	field("precision", strconv.Itoa(c.precision))
+	field("blink", flag(c.blink))
+	field("animate", flag(c.animate))
+	field("name", text(c.name))
+	field("seed", strconv.Itoa(c.seed))
+	field("display", text(c.display))
+	field("variant", text(c.variant.String()))
+	field("expression", text(c.expression.String()))
+	field("colors", list(c.colors)...)
+	field("classes", list(c.classes)...)
+
+	if c.badge == nil {
+		field("badge")
+	} else {
+		field("badge", text(c.badge.corner.String()), text(c.badge.color), text(c.badge.text), text(c.badge.outline))
+	}
+
+	decoration("border", c.border)
+	decoration("storyRing", c.storyRing)
+	field("shadow", flag(c.shadow))
+	field("padding", number(c.padding))
+	field("background", text(c.background))
+	field("rotate", number(c.rotate))
+	field("flipX", flag(c.flipX))
+	field("flipY", flag(c.flipY))
+	field("zoom", number(c.zoom))
+
+	return b.String()
+}
@@ -0,0 +1,229 @@
+package goboringavatars
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// Gallery layout, in svg units.
+const (
+	galleryLabel  = 120 // width of the column of names
+	galleryHeader = 24  // height of the row of variant names
+	galleryTitle  = 32  // height of the palette name above each table
+	galleryGap    = 16
+)
+
+// Palette is a named palette of five colors, for Gallery.
+type Palette struct {
+	Name   string
+	Colors []string
+}
+
+// galleryVariants are the variants of a Gallery that doesn't name any.
+var galleryVariants = []Name{Marble, Beam, Bauhaus, Ring, Sunset, Pixel}
+
+// galleryCell is an avatar in a gallery, with ids that don't clash with the
+// others.
+type galleryCell struct {
+	config
+	x, y float64
+}
+
+// galleryTable is the avatars of a palette, a row for each name and a column
+// for each variant.
+type galleryTable struct {
+	palette Palette
+	y       float64
+	rows    [][]galleryCell
+}
+
+// Gallery generates an HTML page to review palettes, with a table of the names
+// by the variants for each palette. Without variants the table shows all six,
+// and without palettes it shows the palette of the options. The options apply
+// to every avatar.
+func Gallery(names []string, variants []Name, palettes []Palette, opts ...Option) (string, error) {
+
+	tables, err := gallery(names, variants, palettes, opts)
+	if err != nil {
+		return "", err
+	}
+
+	page := strings.Builder{}
+
+	page.WriteString(`<!DOCTYPE html><html><head><meta charset="utf-8"><title>Gallery</title>`)
+	page.WriteString(`<style>body{font-family:sans-serif}th{font-weight:normal;padding:4px 8px}th[scope=row]{text-align:right}small{color:#666}</style>`)
+	page.WriteString(`</head><body>`)
+
+	for _, table := range tables {
+
+		page.WriteString(fmt.Sprintf(`<section><h2>%s<small>%s</small></h2><table><thead><tr><th></th>`, html.EscapeString(table.palette.Name+" "), html.EscapeString(strings.Join(table.palette.Colors, " "))))
+
+		for _, cell := range table.rows[0] {
+			page.WriteString(fmt.Sprintf(`<th scope="col">%s</th>`, variantLabel(cell.variant)))
+		}
+
+		page.WriteString(`</tr></thead><tbody>`)
+
+		for _, row := range table.rows {
+
+			page.WriteString(fmt.Sprintf(`<tr><th scope="row">%s</th>`, html.EscapeString(row[0].display)))
+
+			for _, cell := range row {
+
+				svg, err := cell.render()
+				if err != nil {
+					return "", err
+				}
+
+				page.WriteString(`<td>` + svg + `</td>`)
+			}
+
+			page.WriteString(`</tr>`)
+		}
+
+		page.WriteString(`</tbody></table></section>`)
+	}
+
+	page.WriteString(`</body></html>`)
+
+	return page.String(), nil
+}
+
+// GallerySVG draws the tables of Gallery into a single svg, with the avatars
+// svgSize units wide. Minify and Precision apply to the whole svg.
+func GallerySVG(names []string, variants []Name, palettes []Palette, opts ...Option) (string, error) {
+
+	tables, err := gallery(names, variants, palettes, opts)
+	if err != nil {
+		return "", err
+	}
+
+	c, err := build("gallery", opts...)
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		last   = tables[len(tables)-1]
+		width  = galleryLabel + len(last.rows[0])*(svgSize+galleryGap)
+		height = int(last.y) + galleryTitle + galleryHeader + len(last.rows)*(svgSize+galleryGap)
+		svg    = strings.Builder{}
+	)
+
+	svg.WriteString(fmt.Sprintf(`<svg viewBox="0 0 %d %d" fill="none" xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="sans-serif" font-size="12">`, width, height, width, height))
+
+	for _, table := range tables {
+
+		svg.WriteString(fmt.Sprintf(`<text x="0" y="%s" font-size="16" fill="#000000">%s</text>`, formatNumber(table.y+galleryTitle-12), html.EscapeString(strings.TrimSpace(table.palette.Name+" "+strings.Join(table.palette.Colors, " ")))))
+
+		for _, cell := range table.rows[0] {
+			svg.WriteString(fmt.Sprintf(`<text x="%s" y="%s" text-anchor="middle" fill="#666666">%s</text>`, formatNumber(cell.x+svgSize/2), formatNumber(cell.y-8), variantLabel(cell.variant)))
+		}
+
+		for _, row := range table.rows {
+
+			svg.WriteString(fmt.Sprintf(`<text x="%d" y="%s" text-anchor="end" dominant-baseline="middle" fill="#000000">%s</text>`, galleryLabel-galleryGap/2, formatNumber(row[0].y+svgSize/2), html.EscapeString(row[0].display)))
+
+			for _, cell := range row {
+
+				cell.viewport = &viewport{cell.x, cell.y, svgSize, svgSize}
+
+				avatar, err := cell.render()
+				if err != nil {
+					return "", err
+				}
+
+				svg.WriteString(avatar)
+			}
+		}
+	}
+
+	svg.WriteString(`</svg>`)
+
+	return c.optimize(svg.String())
+}
+
+// gallery builds the avatars of a gallery and places them.
+func gallery(names []string, variants []Name, palettes []Palette, opts []Option) ([]galleryTable, error) {
+
+	if len(names) == 0 {
+		return nil, ErrEmptyName
+	}
+
+	if len(variants) == 0 {
+		variants = galleryVariants
+	}
+
+	for _, variant := range variants {
+		if !ValidateName(variant) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidVariant, variant)
+		}
+	}
+
+	if len(palettes) == 0 {
+
+		c, err := build("gallery", opts...)
+		if err != nil {
+			return nil, err
+		}
+
+		palettes = []Palette{{Name: "Default", Colors: c.colors}}
+	}
+
+	var (
+		tables = make([]galleryTable, 0, len(palettes))
+		y      = 0.0
+	)
+
+	for p, palette := range palettes {
+
+		if len(palette.Colors) != 5 {
+			return nil, fmt.Errorf("palette %q: %w, not %d", palette.Name, ErrInvalidColors, len(palette.Colors))
+		}
+
+		var (
+			colors = Colors(palette.Colors[0], palette.Colors[1], palette.Colors[2], palette.Colors[3], palette.Colors[4])
+			table  = galleryTable{palette: palette, y: y}
+		)
+
+		for n, name := range names {
+
+			row := make([]galleryCell, 0, len(variants))
+
+			for v, variant := range variants {
+
+				c, err := build(name, append(opts[:len(opts):len(opts)], colors, Variant(variant))...)
+				if err != nil {
+					return nil, err
+				}
+
+				c.prefix = fmt.Sprintf("p%dn%dv%d-", p, n, v)
+
+				row = append(row, galleryCell{
+					config: c,
+					x:      float64(galleryLabel + v*(svgSize+galleryGap)),
+					y:      y + galleryTitle + galleryHeader + float64(n*(svgSize+galleryGap)),
+				})
+			}
+
+			table.rows = append(table.rows, row)
+		}
+
+		tables = append(tables, table)
+
+		y += galleryTitle + galleryHeader + float64(len(names)*(svgSize+galleryGap))
+	}
+
+	return tables, nil
+}
+
+// variantLabel returns the name of a variant, as read by ParseName.
+func variantLabel(variant Name) string {
+
+	if variant == Marble {
+		return "marble"
+	}
+
+	return variant.String()
+}
@@ -0,0 +1,166 @@
+package goboringavatars
+
+import (
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Generator renders avatars with a shared set of options, keeping them in a
+// Cache so each avatar is only drawn once.
+type Generator struct {
+	cache Cache
+	opts  []Option
+}
+
+// NewGenerator returns a Generator that applies the options to every avatar.
+// Without a cache, every avatar is drawn afresh.
+func NewGenerator(cache Cache, opts ...Option) *Generator {
+	return &Generator{cache: cache, opts: opts}
+}
+
+// New generates an avatar for the given name, with the options of the
+// Generator followed by these ones.
+func (g *Generator) New(name string, opts ...Option) (string, error) {
+
+	c, err := g.build(name, opts...)
+	if err != nil {
+		return "", err
+	}
+
+	return g.render(c, c.fingerprint())
+}
+
+// Render returns an Avatar struct that contains the boring avatar and a potential error.
+func (g *Generator) Render(name string, opts ...Option) Avatar {
+
+	result, err := g.New(name, opts...)
+	return Avatar{result, err}
+
+}
+
+// Fingerprint returns the Fingerprint of the avatar New would generate.
+func (g *Generator) Fingerprint(name string, opts ...Option) (string, error) {
+
+	c, err := g.build(name, opts...)
+	if err != nil {
+		return "", err
+	}
+
+	return c.fingerprint(), nil
+}
+
+// build returns the config for the name, with the options of the Generator
+// followed by these ones.
+func (g *Generator) build(name string, opts ...Option) (config, error) {
+	return build(name, append(slices.Clip(g.opts), opts...)...)
+}
+
+// render draws the avatar, or returns it from the cache.
+func (g *Generator) render(c config, key string) (string, error) {
+
+	if g.cache == nil {
+		return c.render()
+	}
+
+	if svg, ok := g.cache.Get(key); ok {
+		return svg, nil
+	}
+
+	svg, err := c.render()
+	if err != nil {
+		return "", err
+	}
+
+	g.cache.Set(key, svg)
+
+	return svg, nil
+}
+
+// ServeHTTP serves the avatar named by the last element of the path, such as
+// /avatars/Mary%20Baker.svg?variant=beam, as image/svg+xml. The query is read
+// by ParseQuery, and its options follow those of the Generator. The ETag is the Fingerprint of
+// the avatar, so clients that already have it get 304 Not Modified.
+func (g *Generator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimSuffix(path.Base(r.URL.Path), ".svg")
+
+	if name == "/" || name == "." {
+		name = ""
+	}
+
+	query, err := ParseQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	opts, err := query.Options()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c, err := g.build(name, opts...)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var (
+		key  = c.fingerprint()
+		etag = `"` + key + `"`
+	)
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+
+	if match(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	svg, err := g.render(c, key)
+	if err != nil {
+
+		// The options of the Generator draw other avatars, so a failure with
+		// options from the query is down to the query.
+		status := http.StatusInternalServerError
+		if len(opts) > 0 {
+			status = http.StatusBadRequest
+		}
+
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	_, _ = w.Write([]byte(svg))
+}
+
+// match reports whether an If-None-Match header lists the ETag.
+func match(header, etag string) bool {
+
+	for _, tag := range strings.Split(header, ",") {
+
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+
+		if tag == etag || tag == "*" {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,197 @@
+package goboringavatars
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// groupLimit is the most members a group shows.
+const groupLimit = 4
+
+// groupGap is the space between the members of a group, in svg units.
+const groupGap = 2
+
+// Layout limits how the members of a group are arranged.
+type Layout struct {
+	layout string
+}
+
+func (l Layout) String() string {
+	return l.layout
+}
+
+func ValidateLayout(layout Layout) bool {
+	switch layout.String() {
+	case "stack", "grid", "pie":
+		return true
+	default:
+		return false
+	}
+}
+
+var (
+	Stack = Layout{"stack"} // Stack overlaps the members in a row, each cut away where the next one sits.
+	Grid  = Layout{"grid"}  // Grid splits the avatar into halves and quarters.
+	Pie   = Layout{"pie"}   // Pie splits the avatar into equal slices.
+)
+
+// Group generates a single avatar for a group from the avatars of the first
+// four names. Every member is drawn from its own name, with the same options,
+// while Size, Title, Classes, Badge and the decorations apply to the group
+// as a whole.
+func Group(names []string, layout Layout, opts ...Option) (string, error) {
+
+	if !ValidateLayout(layout) {
+		return "", ErrInvalidLayout
+	}
+
+	if len(names) == 0 {
+		return "", ErrEmptyName
+	}
+
+	names = names[:min(len(names), groupLimit)]
+
+	c, err := build(strings.Join(names, ", "), opts...)
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		svg     = strings.Builder{}
+		defs    = []string{}
+		members = make([]config, 0, len(names))
+		areas   = groupAreas(layout, len(names))
+	)
+
+	// Ids of different groups on the same page must not clash, even when only their shapes differ.
+	c.prefix = fmt.Sprintf("g%d-%s-", reseed(hashCode(strings.Join(names, "\n")+c.outline(0, 0, svgSize, brush{})), c.seed), layout)
+
+	for i, name := range names {
+
+		m, err := build(name, opts...)
+		if err != nil {
+			return "", err
+		}
+
+		m.prefix = c.prefix + fmt.Sprintf("m%d-", i)
+		m.viewport = &areas[i]
+		m.badge, m.border, m.storyRing, m.shadow = nil, nil, nil, false
+
+		// Only stacked members keep their own shape, the group clips the rest.
+		if layout != Stack {
+			m.square = true
+		}
+
+		members = append(members, m)
+	}
+
+	// A stack runs to the edges of the svg, so the group itself isn't clipped.
+	if layout == Stack {
+		c.square = true
+	}
+
+	c.start(&svg, "group", svgSize)
+
+	for i, m := range members {
+
+		var mask string
+
+		switch {
+		case layout == Stack && i < len(members)-1:
+
+			next := areas[i+1]
+
+			// The cut follows the shape of the next member, with a gap around it.
+			mask = fmt.Sprintf(`<rect width="%d" height="%d" fill="#FFFFFF"></rect>%s`, svgSize, svgSize, members[i+1].outline(next.x-groupGap, next.y-groupGap, next.width+groupGap*2, brush{fill: "#000000"}))
+
+		case layout == Pie && len(members) > 1:
+			mask = slice(i, len(members))
+		}
+
+		member, err := m.render()
+		if err != nil {
+			return "", err
+		}
+
+		if mask == "" {
+			svg.WriteString(member)
+			continue
+		}
+
+		id := c.id(fmt.Sprintf("cut%d", i))
+
+		defs = append(defs, fmt.Sprintf(`<mask id="%s" maskUnits="userSpaceOnUse" x="0" y="0" width="%d" height="%d">%s</mask>`, id, svgSize, svgSize, mask))
+
+		svg.WriteString(fmt.Sprintf(`<g mask="url(#%s)">%s</g>`, id, member))
+	}
+
+	c.end(&svg, svgSize, defs...)
+
+	return c.optimize(svg.String())
+}
+
+// groupAreas returns where each of the n members of a group is drawn.
+func groupAreas(layout Layout, n int) []viewport {
+
+	const size = float64(svgSize)
+
+	areas := make([]viewport, 0, n)
+
+	switch layout {
+	case Stack:
+
+		// Each member overlaps the previous one by 30% of its width.
+		width := size / (1 + 0.7*float64(n-1))
+
+		for i := 0; i < n; i++ {
+			areas = append(areas, viewport{0.7 * width * float64(i), (size - width) / 2, width, width})
+		}
+
+	case Grid:
+
+		half := (size - groupGap) / 2
+		far := half + groupGap
+
+		switch n {
+		case 1:
+			areas = append(areas, viewport{0, 0, size, size})
+		case 2:
+			areas = append(areas, viewport{0, 0, half, size}, viewport{far, 0, half, size})
+		case 3:
+			areas = append(areas, viewport{0, 0, half, size}, viewport{far, 0, half, half}, viewport{far, far, half, half})
+		default:
+			areas = append(areas, viewport{0, 0, half, half}, viewport{far, 0, half, half}, viewport{0, far, half, half}, viewport{far, far, half, half})
+		}
+
+	default:
+		for i := 0; i < n; i++ {
+			areas = append(areas, viewport{0, 0, size, size})
+		}
+	}
+
+	return areas
+}
+
+// slice returns the mask of the ith of n pie slices, starting at the top and
+// going clockwise. The outline keeps a gap between neighbouring slices.
+func slice(i, n int) string {
+
+	const (
+		center = float64(svgSize) / 2
+		radius = float64(svgSize) // past the corners of the svg
+	)
+
+	point := func(k int) (float64, float64) {
+		angle := 2*math.Pi*float64(k)/float64(n) - math.Pi/2
+		return center + radius*math.Cos(angle), center + radius*math.Sin(angle)
+	}
+
+	var (
+		x0, y0 = point(i)
+		x1, y1 = point(i + 1)
+	)
+
+	// Slices never pass half of the circle, so the arc is never the large one.
+	return fmt.Sprintf(`<path d="M%s %sL%s %sA%s %s 0 0 1 %s %sZ" fill="#FFFFFF" stroke="#000000" stroke-width="%d"></path>`, formatNumber(center), formatNumber(center), formatNumber(x0), formatNumber(y0), formatNumber(radius), formatNumber(radius), formatNumber(x1), formatNumber(y1), groupGap)
+}
@@ -0,0 +1,204 @@
+package goboringavatars
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image/png"
+	"io"
+	"path"
+)
+
+// Sizes, in pixels, of the images packed into each icon format.
+var (
+	icoSizes      = []int{16, 32, 48, 64, 128, 256}
+	manifestSizes = []int{192, 512}
+	icnsTypes     = []struct {
+		kind string
+		size int
+	}{
+		{"icp4", 16},
+		{"icp5", 32},
+		{"ic11", 32}, // 16@2x
+		{"icp6", 64},
+		{"ic12", 64}, // 32@2x
+		{"ic07", 128},
+		{"ic08", 256},
+		{"ic13", 256}, // 128@2x
+		{"ic09", 512},
+		{"ic14", 512}, // 256@2x
+	}
+)
+
+// png renders the avatar as a PNG of px by px pixels.
+func (c config) png(px int) ([]byte, error) {
+
+	if px <= 0 {
+		return nil, ErrInvalidPixels
+	}
+
+	svg, err := c.render()
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := rasterize(svg, px)
+	if err != nil {
+		return nil, err
+	}
+
+	b := bytes.NewBuffer(nil)
+
+	if err := png.Encode(b, img); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// pngs renders the avatar once for each distinct size.
+func (c config) pngs(sizes ...int) (map[int][]byte, error) {
+
+	images := make(map[int][]byte, len(sizes))
+
+	for _, size := range sizes {
+
+		if _, ok := images[size]; ok {
+			continue
+		}
+
+		b, err := c.png(size)
+		if err != nil {
+			return nil, err
+		}
+
+		images[size] = b
+	}
+
+	return images, nil
+}
+
+// EncodeICO writes the avatar for the name to w as a multi-resolution .ico
+// file holding 16, 32, 48, 64, 128 and 256 pixel images, for use as a favicon.
+func EncodeICO(w io.Writer, name string, opts ...Option) error {
+
+	c, err := build(name, opts...)
+	if err != nil {
+		return err
+	}
+
+	images, err := c.pngs(icoSizes...)
+	if err != nil {
+		return err
+	}
+
+	var (
+		header  = bytes.NewBuffer(nil)
+		data    = bytes.NewBuffer(nil)
+		offset  = 6 + 16*len(icoSizes)
+		entries = []any{uint16(0), uint16(1), uint16(len(icoSizes))}
+	)
+
+	for _, size := range icoSizes {
+
+		// A width and height of 0 means 256 pixels.
+		dimension := uint8(size % 256)
+
+		entries = append(entries, dimension, dimension, uint8(0), uint8(0), uint16(1), uint16(32), uint32(len(images[size])), uint32(offset+data.Len()))
+
+		data.Write(images[size])
+	}
+
+	for _, v := range entries {
+		if err := binary.Write(header, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+
+	_, err = io.Copy(w, io.MultiReader(header, data))
+
+	return err
+}
+
+// EncodeICNS writes the avatar for the name to w as an Apple .icns file
+// holding PNG images from 16 to 512 pixels, including the retina sizes.
+func EncodeICNS(w io.Writer, name string, opts ...Option) error {
+
+	c, err := build(name, opts...)
+	if err != nil {
+		return err
+	}
+
+	sizes := make([]int, 0, len(icnsTypes))
+	for _, t := range icnsTypes {
+		sizes = append(sizes, t.size)
+	}
+
+	images, err := c.pngs(sizes...)
+	if err != nil {
+		return err
+	}
+
+	body := bytes.NewBuffer(nil)
+
+	for _, t := range icnsTypes {
+		body.WriteString(t.kind)
+		body.Write(binary.BigEndian.AppendUint32(nil, uint32(8+len(images[t.size]))))
+		body.Write(images[t.size])
+	}
+
+	out := bytes.NewBufferString("icns")
+	out.Write(binary.BigEndian.AppendUint32(nil, uint32(8+body.Len())))
+	out.Write(body.Bytes())
+
+	_, err = w.Write(out.Bytes())
+
+	return err
+}
+
+// ManifestIcon is an entry of the icons list of a web app manifest, along
+// with the PNG it points at.
+type ManifestIcon struct {
+	Src     string `json:"src"`
+	Sizes   string `json:"sizes"`
+	Type    string `json:"type"`
+	Purpose string `json:"purpose,omitempty"`
+	Data    []byte `json:"-"`
+}
+
+// ManifestIcons renders the 192 and 512 pixel icons a web app manifest asks
+// for. Each icon is served from dir, so encoding the result as JSON gives the
+// manifest's icons list. Square avatars fill the whole icon, and are marked
+// as safe for platforms that mask icons to their own shape.
+func ManifestIcons(name, dir string, opts ...Option) ([]ManifestIcon, error) {
+
+	c, err := build(name, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	images, err := c.pngs(manifestSizes...)
+	if err != nil {
+		return nil, err
+	}
+
+	icons := make([]ManifestIcon, 0, len(manifestSizes))
+
+	for _, size := range manifestSizes {
+
+		icon := ManifestIcon{
+			Src:   path.Join(dir, fmt.Sprintf("icon-%d.png", size)),
+			Sizes: fmt.Sprintf("%dx%d", size, size),
+			Type:  "image/png",
+			Data:  images[size],
+		}
+
+		if c.square {
+			icon.Purpose = "any maskable"
+		}
+
+		icons = append(icons, icon)
+	}
+
+	return icons, nil
+}
@@ -0,0 +1,68 @@
+package goboringavatars
+
+import (
+	"image"
+	"image/color"
+	"sync"
+)
+
+// Image is an avatar as an image.Image, so it can be drawn onto other images
+// with image/draw. The avatar is rasterized the first time its pixels are
+// read. Pixels outside of the mask are transparent, unless Square is used.
+type Image struct {
+	svg  string
+	size int
+	once sync.Once
+	img  *image.RGBA
+	err  error
+}
+
+// NewImage generates an avatar for the given name as a px by px image.
+func NewImage(name string, px int, opts ...Option) (*Image, error) {
+	return Render(name, opts...).Image(px)
+}
+
+// Image returns the Avatar as a px by px image.
+func (a Avatar) Image(px int) (*Image, error) {
+
+	if a.err != nil {
+		return nil, a.err
+	}
+
+	if px <= 0 {
+		return nil, ErrInvalidPixels
+	}
+
+	return &Image{svg: a.raw, size: px}, nil
+}
+
+// RGBA returns the rasterized avatar, or the error that stopped it from being drawn.
+func (i *Image) RGBA() (*image.RGBA, error) {
+
+	i.once.Do(func() {
+		i.img, i.err = rasterize(i.svg, i.size)
+	})
+
+	return i.img, i.err
+}
+
+// ColorModel returns the color model of the image.
+func (i *Image) ColorModel() color.Model {
+	return color.RGBAModel
+}
+
+// Bounds returns the size of the image, without rasterizing it.
+func (i *Image) Bounds() image.Rectangle {
+	return image.Rect(0, 0, i.size, i.size)
+}
+
+// At returns the color of a pixel, which is transparent if the avatar could not be drawn.
+func (i *Image) At(x, y int) color.Color {
+
+	img, err := i.RGBA()
+	if err != nil {
+		return color.RGBA{}
+	}
+
+	return img.RGBAAt(x, y)
+}
@@ -2,9 +2,12 @@ package goboringavatars
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"html"
 	"io"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -17,10 +20,26 @@ const (
 
 // Custom errors
 var (
-	ErrNegativePixels = errors.New("pixels can not be negative")
-	ErrInvalidVariant = errors.New("invalid variant")
-	ErrEmptyName      = errors.New("name is empty")
-	defaultColors     = []string{"#0A0310", "#49007E", "#FF005B", "#FF7D10", "#FFB238"}
+	ErrNegativePixels   = errors.New("pixels can not be negative")
+	ErrInvalidVariant   = errors.New("invalid variant")
+	ErrEmptyName        = errors.New("name is empty")
+	ErrInvalidMood      = errors.New("invalid expression")
+	ErrInvalidPixels    = errors.New("pixels must be greater than zero")
+	ErrInvalidLayout    = errors.New("invalid layout")
+	ErrInvalidCorner    = errors.New("invalid corner")
+	ErrInvalidShape     = errors.New("invalid shape")
+	ErrInvalidWidth     = errors.New("invalid width")
+	ErrInvalidTransform = errors.New("invalid transform")
+	ErrInvalidLang      = errors.New("invalid language tag")
+	ErrInvalidPrecision = errors.New("precision must be between 0 and 10 decimals")
+	ErrInvalidFile      = errors.New("invalid file name")
+	ErrInvalidSize      = errors.New("invalid size")
+	ErrInvalidColors    = errors.New("colors must be a list of five")
+	ErrInvalidColor     = errors.New("invalid color")
+	ErrInvalidUnit      = errors.New("invalid unit")
+	ErrEmptyPalette     = errors.New("palette has no colors")
+	ErrInvalidClass     = errors.New("invalid class")
+	defaultColors       = []string{"#0A0310", "#49007E", "#FF005B", "#FF7D10", "#FFB238"}
 )
 
 // Name limits the styles used.
@@ -50,15 +69,78 @@ var (
 	Sunset  = Name{"sunset"}
 )
 
+// Mood limits the expressions a Beam avatar can make.
+type Mood struct {
+	mood string
+}
+
+func (m Mood) String() string {
+	return m.mood
+}
+
+func ValidateMood(mood Mood) bool {
+	switch mood.String() {
+	case "", "happy", "sad", "surprised", "sleeping", "winking", "neutral":
+		return true
+	default:
+		return false
+	}
+}
+
+var (
+	Derived   = Mood{""} // Derived picks the expression from the name, and is the default Mood.
+	Happy     = Mood{"happy"}
+	Neutral   = Mood{"neutral"}
+	Sad       = Mood{"sad"}
+	Sleeping  = Mood{"sleeping"}
+	Surprised = Mood{"surprised"}
+	Winking   = Mood{"winking"}
+)
+
 // Config
 type config struct {
-	size    string
-	square  bool
-	title   bool
-	name    string
-	variant Name
-	colors  []string
-	classes []string
+	width       string // empty leaves the attribute out, so the svg fills its container
+	height      string
+	square      bool
+	shape       Mask
+	title       bool
+	label       string
+	description string
+	lang        string
+	decorative  bool
+	private     bool
+	minify      bool
+	precision   int // decimals of the numbers in the output, or -1 to leave them
+	blink       bool
+	animate     bool
+	frozen      bool    // draw the animation as it is at time, without CSS
+	time        float64 // seconds into the animation when frozen
+	name        string  // hashed to draw the avatar, after the normalizers
+	seed        int
+	display     string // the name as it was given, for the title
+	normalizers []Normalizer
+	variant     Name
+	candidates  []Name // the variant is picked from these by the name, when set
+	expression  Mood
+	colors      []string
+	classes     []string
+	badge       *badge
+	border      *decoration
+	storyRing   *decoration
+	shadow      bool
+	padding     float64 // percentage of the width on each side
+	background  string
+	rotate      float64
+	flipX       bool
+	flipY       bool
+	zoom        float64   // 0 leaves the artwork at its size
+	prefix      string    // namespaces the ids, so avatars can share an svg
+	viewport    *viewport // where a nested avatar is drawn, in its parent's units
+}
+
+// viewport is the area of a group a member avatar fills.
+type viewport struct {
+	x, y, width, height float64
 }
 
 type option func(*config) error
@@ -72,25 +154,85 @@ type Option interface {
 	apply(*config) error
 }
 
-// Size sets the size of the Avatar.
+// Size sets the width and height of the Avatar, in a CSS length unit such as
+// "px", "em", "rem", "%" or "vw", or in user units when the unit is empty.
 func Size(size float64, unit string) Option {
 	return option(func(c *config) error {
 
-		if size < 0 {
-			return ErrNegativePixels
+		length, err := length("Size", size, unit)
+		if err != nil {
+			return err
+		}
+
+		c.width, c.height = length, length
+
+		return nil
+	})
+}
+
+// Width sets the width of the Avatar, in the units of Size. The artwork stays
+// square, centered in the space left when the height differs.
+func Width(size float64, unit string) Option {
+	return option(func(c *config) error {
+
+		length, err := length("Width", size, unit)
+		if err != nil {
+			return err
 		}
 
-		s := strings.Builder{}
+		c.width = length
+
+		return nil
+	})
+}
+
+// Height sets the height of the Avatar, in the units of Size.
+func Height(size float64, unit string) Option {
+	return option(func(c *config) error {
 
-		s.WriteString(strconv.FormatFloat(size, 'f', -1, 64))
-		s.WriteString(strings.ToLower(unit))
+		length, err := length("Height", size, unit)
+		if err != nil {
+			return err
+		}
 
-		c.size = s.String()
+		c.height = length
 
 		return nil
 	})
 }
 
+// Responsive leaves the width and height out of the svg, so it scales to fill
+// its container, or is sized by CSS.
+func Responsive() Option {
+	return option(func(c *config) error {
+		c.width, c.height = "", ""
+		return nil
+	})
+}
+
+// length returns the size with its unit, as written in the svg.
+func length(option string, size float64, unit string) (string, error) {
+
+	if !finite(size) {
+		return "", &OptionError{option, size, ErrInvalidSize}
+	}
+
+	if size < 0 {
+		return "", &OptionError{option, size, ErrNegativePixels}
+	}
+
+	if !validUnit(unit) {
+		return "", &OptionError{option, unit, ErrInvalidUnit}
+	}
+
+	s := strings.Builder{}
+
+	s.WriteString(strconv.FormatFloat(size, 'f', -1, 64))
+	s.WriteString(strings.ToLower(unit))
+
+	return s.String(), nil
+}
+
 // Square makes the Avatar square.
 func Square() Option {
 	return option(func(c *config) error {
@@ -99,7 +241,7 @@ func Square() Option {
 	})
 }
 
-// Title add a title element with the name to the SVG output.
+// Title adds a title element with the name, or the Label, to the SVG output.
 func Title() Option {
 	return option(func(c *config) error {
 		c.title = true
@@ -107,34 +249,134 @@ func Title() Option {
 	})
 }
 
+// Private keeps the name out of the SVG output. Ids are made from its hash,
+// and Title shows nothing unless the title is set with Label.
+func Private() Option {
+	return option(func(c *config) error {
+		c.private = true
+		return nil
+	})
+}
+
 // Variant sets the specific variant to be used for the Avatar.
 func Variant(variant Name) Option {
 	return option(func(c *config) error {
 
 		if !ValidateName(variant) {
-			return ErrInvalidVariant
+			return &OptionError{"Variant", variant, ErrInvalidVariant}
 		}
 
 		c.variant = variant
+		c.candidates = nil
+
+		return nil
+	})
+}
+
+// VariantFromHash picks the variant of the Avatar from the name, out of the
+// candidates or all of the variants when there are none. The pick uses its own
+// hash of the name, so it doesn't follow the colors.
+func VariantFromHash(candidates ...Name) Option {
+
+	// The option is shared, such as by a Generator, so the list is settled
+	// here and only read when it is applied.
+	var err error
+
+	for _, variant := range candidates {
+		if !ValidateName(variant) {
+			err = &OptionError{"VariantFromHash", variant, ErrInvalidVariant}
+			break
+		}
+	}
+
+	if len(candidates) == 0 {
+		candidates = []Name{Marble, Beam, Bauhaus, Ring, Sunset, Pixel}
+	} else {
+		candidates = slices.Clone(candidates)
+	}
+
+	return option(func(c *config) error {
+
+		if err != nil {
+			return err
+		}
+
+		c.candidates = candidates
 
 		return nil
 	})
 }
 
-// Colors sets the five (5) colors that will be used to generate the Avatar.
+// Expression sets the expression of a Beam avatar. Other variants ignore it.
+func Expression(mood Mood) Option {
+	return option(func(c *config) error {
+
+		if !ValidateMood(mood) {
+			return &OptionError{"Expression", mood, ErrInvalidMood}
+		}
+
+		c.expression = mood
+
+		return nil
+	})
+}
+
+// Blink makes the eyes of a Beam avatar blink. Other variants ignore it.
+func Blink() Option {
+	return option(func(c *config) error {
+		c.blink = true
+		return nil
+	})
+}
+
+// Animate makes the Avatar move: marble blobs rotate, ring bands pulse, the
+// sunset horizon drifts, pixel cells shimmer and beam faces blink, while
+// Bauhaus avatars stay still. The motion is derived from the name and stops
+// for visitors that prefer reduced motion.
+func Animate() Option {
+	return option(func(c *config) error {
+		c.animate = true
+		return nil
+	})
+}
+
+// Colors sets the five (5) colors that will be used to generate the Avatar,
+// as CSS colors: hex colors such as "#FF005B" or "#F05", named colors such as
+// "teal", or the rgb and hsl functions.
 func Colors(one, two, three, four, five string) Option {
 	return option(func(c *config) error {
 
-		c.colors = []string{one, two, three, four, five}
+		colors := []string{one, two, three, four, five}
+
+		if strings.Join(colors, "") == "" {
+			return &OptionError{"Colors", "", ErrEmptyPalette}
+		}
+
+		for _, color := range colors {
+			if !validColor(color) {
+				return &OptionError{"Colors", color, ErrInvalidColor}
+			}
+		}
+
+		c.colors = colors
 
 		return nil
 	})
 }
 
-// Classes adds classes to the svg.
+// Classes adds classes to the svg. Each one is a single class name, without
+// spaces, quotes or angle brackets.
 func Classes(list ...string) Option {
 	return option(func(c *config) error {
+
+		for _, class := range list {
+			if !validClass(class) {
+				return &OptionError{"Classes", class, ErrInvalidClass}
+			}
+		}
+
 		c.classes = append(c.classes, list...)
+
 		return nil
 	})
 }
@@ -149,15 +391,29 @@ func returnErr(msg string) Option {
 // New generates an avatar for the given name.
 func New(name string, opts ...Option) (string, error) {
 
+	c, err := build(name, opts...)
+	if err != nil {
+		return "", err
+	}
+
+	return c.render()
+
+}
+
+// build returns the config for the name after applying the options.
+func build(name string, opts ...Option) (config, error) {
+
 	if name == "" {
-		return "", ErrEmptyName
+		return config{}, ErrEmptyName
 	}
 
 	var (
 		c = config{
-			name:   name,
-			size:   "40",
-			colors: defaultColors,
+			name:      name,
+			width:     "40",
+			height:    "40",
+			colors:    defaultColors,
+			precision: -1,
 		}
 		err error
 	)
@@ -166,10 +422,41 @@ func New(name string, opts ...Option) (string, error) {
 		err = errors.Join(err, opt.apply(&c))
 	}
 
+	if err != nil {
+		return config{}, err
+	}
+
+	c.display = name
+	c.name = c.normalize(name)
+
+	if c.name == "" {
+		return config{}, ErrEmptyName
+	}
+
+	if len(c.candidates) > 0 {
+		c.variant = c.candidates[variantHash(c.name, c.seed)%uint32(len(c.candidates))]
+		c.candidates = nil
+	}
+
+	return c, nil
+
+}
+
+// render draws the configured variant.
+func (c config) render() (string, error) {
+
+	svg, err := c.draw()
 	if err != nil {
 		return "", err
 	}
 
+	return c.optimize(svg)
+
+}
+
+// draw writes the svg of the configured variant.
+func (c config) draw() (string, error) {
+
 	switch c.variant {
 	case Beam:
 		return c.beam()
@@ -215,6 +502,70 @@ func (a Avatar) String() string {
 	return a.raw
 }
 
+// DataURI returns the Avatar as a percent-encoded data URI, for use in the src
+// attribute of an img element. Only the characters that need it are escaped,
+// and double quotes are swapped for single ones, so the result is smaller than
+// the base64 form and can sit inside a double quoted attribute.
+func (a Avatar) DataURI() string {
+
+	if a.err != nil {
+		return ""
+	}
+
+	return "data:image/svg+xml," + escapeDataURI(a.raw)
+}
+
+// Base64DataURI returns the Avatar as a base64 encoded data URI.
+func (a Avatar) Base64DataURI() string {
+
+	if a.err != nil {
+		return ""
+	}
+
+	return "data:image/svg+xml;base64," + base64.StdEncoding.EncodeToString([]byte(a.raw))
+}
+
+// CSSURL returns the Avatar as a CSS url() value, such as for background-image.
+func (a Avatar) CSSURL() string {
+
+	if a.err != nil {
+		return ""
+	}
+
+	return `url("` + a.DataURI() + `")`
+}
+
+// escapeDataURI percent-encodes the characters of the svg that are not safe in
+// a data URI, or in a quoted attribute or CSS string holding one.
+func escapeDataURI(svg string) string {
+
+	var (
+		s   = strings.Builder{}
+		hex = "0123456789ABCDEF"
+	)
+
+	s.Grow(len(svg))
+
+	for i := 0; i < len(svg); i++ {
+
+		c := svg[i]
+
+		switch {
+		case c == '"':
+			s.WriteByte('\'')
+		case c < 0x20, c >= 0x7f, strings.IndexByte(`%#&<>'\`, c) >= 0:
+			s.WriteByte('%')
+			s.WriteByte(hex[c>>4])
+			s.WriteByte(hex[c&0xf])
+		default:
+			s.WriteByte(c)
+		}
+
+	}
+
+	return s.String()
+}
+
 // Render returns an Avatar struct that contains the boring avatar and a potential error.
 func Render(name string, opts ...Option) Avatar {
 
@@ -225,34 +576,77 @@ func Render(name string, opts ...Option) Avatar {
 
 func (a config) start(svg *strings.Builder, maskID string, size int) {
 
-	svg.WriteString(fmt.Sprintf(`<svg viewBox="0 0 %d %d" fill="none" role="img" xmlns="http://www.w3.org/2000/svg" width="%s" height="%s"`, size, size, a.size, a.size))
+	mask := a.outline(0, 0, float64(size), brush{fill: "#FFFFFF"})
 
-	if len(a.classes) > 0 {
-		svg.WriteString(fmt.Sprintf(` class="%s"`, strings.Join(a.classes, " ")))
+	// Avatars cut to other shapes get their own mask, so that inline svgs of
+	// the same variant don't share the outline of whichever comes first.
+	if a.square || a.shape != Circle {
+		maskID = fmt.Sprintf("%s_%d", maskID, hashCode(mask))
 	}
 
-	svg.WriteString(`>`)
+	maskID = a.id(maskID)
+
+	if v := a.viewport; v != nil {
+
+		// A nested avatar covers its area, cropping the sides of wide or tall ones.
+		svg.WriteString(fmt.Sprintf(`<svg x="%s" y="%s" width="%s" height="%s" viewBox="0 0 %d %d" preserveAspectRatio="xMidYMid slice">`, formatNumber(v.x), formatNumber(v.y), formatNumber(v.width), formatNumber(v.height), size, size))
+
+	} else {
+
+		svg.WriteString(fmt.Sprintf(`<svg viewBox="0 0 %d %d" fill="none"`, size, size))
 
-	// Add the title
-	if a.title {
-		svg.WriteString(fmt.Sprintf(`<title>%s</title>`, a.name))
+		labels := a.accessible(svg)
+
+		svg.WriteString(` xmlns="http://www.w3.org/2000/svg"`)
+
+		if a.width != "" {
+			svg.WriteString(fmt.Sprintf(` width="%s"`, a.width))
+		}
+
+		if a.height != "" {
+			svg.WriteString(fmt.Sprintf(` height="%s"`, a.height))
+		}
+
+		if len(a.classes) > 0 {
+			svg.WriteString(fmt.Sprintf(` class="%s"`, html.EscapeString(strings.Join(a.classes, " "))))
+		}
+
+		svg.WriteString(`>`)
+
+		// Add the title and description
+		svg.WriteString(labels)
 	}
 
 	// Add the mask
-	svg.WriteString(fmt.Sprintf(`<mask id="%s" maskUnits="userSpaceOnUse" x="0" y="0" width="%d" height="%d"><rect width="%d" height="%d" `, maskID, size, size, size, size))
+	svg.WriteString(fmt.Sprintf(`<mask id="%s" maskUnits="userSpaceOnUse" x="0" y="0" width="%d" height="%d">%s</mask>`, maskID, size, size, mask))
 
-	if !a.square {
-		svg.WriteString(fmt.Sprintf(`rx="%d" `, size*2))
-	}
+	a.decorate(svg, size)
+
+	svg.WriteString(fmt.Sprintf(`<g mask="url(#%s)">`, maskID))
 
-	svg.WriteString(fmt.Sprintf(`fill="#FFFFFF"></rect></mask><g mask="url(#%s)">`, maskID))
+	a.compose(svg, maskID, size)
 
 }
 
-func (a config) end(svg *strings.Builder, filters ...string) {
+func (a config) end(svg *strings.Builder, size int, filters ...string) {
+
+	a.uncompose(svg, size)
+
+	filters = append(filters, a.drawBorder(svg, size)...)
 
 	svg.WriteString(`</g>`)
 
+	filters = append(filters, a.undecorate(svg, size)...)
+
+	// The badge sits outside of the mask, so the rounded corners don't cut it off.
+	if a.badge != nil {
+		a.badge.draw(svg, float64(size))
+	}
+
+	filters = slices.DeleteFunc(filters, func(line string) bool {
+		return line == ""
+	})
+
 	if len(filters) > 0 {
 
 		svg.WriteString(`<defs>`)
@@ -268,3 +662,14 @@ func (a config) end(svg *strings.Builder, filters ...string) {
 	svg.WriteString(`</svg>`)
 
 }
+
+// classPrefix returns a CSS class prefix unique to the name, so styles embedded
+// in one avatar don't leak into others on the same page.
+func (a config) classPrefix() string {
+	return "ba" + strconv.Itoa(a.hash())
+}
+
+// id namespaces an id used inside the svg.
+func (a config) id(name string) string {
+	return a.prefix + name
+}
@@ -2,12 +2,33 @@ package goboringavatars
 
 import (
 	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
+	"html"
+	"html/template"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
 	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"path/filepath"
+	"reflect"
 	"regexp"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -423,3 +444,2011 @@ func TestSizing(t *testing.T) {
 	}
 
 }
+
+func TestExpression(t *testing.T) {
+
+	seen := map[string]Mood{}
+
+	for _, mood := range []Mood{Derived, Happy, Neutral, Sad, Sleeping, Surprised, Winking} {
+		t.Run(mood.String(), func(t *testing.T) {
+
+			got, err := New("Mary Baker", Variant(Beam), Expression(mood))
+			if err != nil {
+				t.Errorf("New() error = %v", err)
+				return
+			}
+
+			face, err := getInternals(got)
+			if err != nil {
+				t.Errorf("received error for rendered: %v", err)
+				return
+			}
+
+			if other, ok := seen[face]; ok && mood != Derived && other != Derived {
+				t.Errorf("%s renders the same face as %s", mood, other)
+			}
+
+			seen[face] = mood
+
+		})
+	}
+
+	if _, err := New("Mary Baker", Expression(Mood{"grumpy"})); !errors.Is(err, ErrInvalidMood) {
+		t.Errorf("invalid expression returned %v", err)
+	}
+
+	blinking, err := New("Mary Baker", Variant(Beam), Blink())
+	if err != nil {
+		t.Errorf("New() error = %v", err)
+		return
+	}
+
+	if !strings.Contains(blinking, "@keyframes") || !strings.Contains(blinking, "prefers-reduced-motion") {
+		t.Errorf("blinking avatar is missing its animation\n%s", blinking)
+	}
+
+	still, _ := New("Mary Baker", Variant(Beam))
+	if strings.Contains(still, "<style>") {
+		t.Errorf("avatar without Blink() has a style element\n%s", still)
+	}
+
+}
+
+func TestAnimate(t *testing.T) {
+	for _, variant := range []Name{Marble, Beam, Pixel, Ring, Sunset} {
+		t.Run(variant.String(), func(t *testing.T) {
+
+			got, err := New("Mary Baker", Variant(variant), Animate())
+			if err != nil {
+				t.Errorf("New() error = %v", err)
+				return
+			}
+
+			if !strings.Contains(got, "@keyframes") || !strings.Contains(got, "@media (prefers-reduced-motion:reduce)") {
+				t.Errorf("%s is not animated\n%s", variant, got)
+			}
+
+			decoder := xml.NewDecoder(strings.NewReader(got))
+			for {
+				if _, err := decoder.Token(); err != nil {
+					if err.Error() != "EOF" {
+						t.Errorf("%s is not well formed: %v", variant, err)
+					}
+					break
+				}
+			}
+
+			again, _ := New("Mary Baker", Variant(variant), Animate())
+			if got != again {
+				t.Errorf("%s animation is not deterministic", variant)
+			}
+
+			other, _ := New("Amelia Earhart", Variant(variant), Animate())
+			if strings.Contains(other, config{name: "Mary Baker"}.classPrefix()) {
+				t.Errorf("%s animation classes are shared between names", variant)
+			}
+
+		})
+	}
+}
+
+func TestMotion(t *testing.T) {
+
+	m := motion{duration: 4, offset: 1, frames: []keyframe{{0, 0}, {0.5, 10}, {1, 0}}}
+
+	tests := []struct {
+		at   float64
+		want float64
+	}{
+		{at: 0, want: 5},
+		{at: 1, want: 10},
+		{at: 2, want: 5},
+		{at: 3, want: 0},
+		{at: 5, want: 10},
+		{at: -1, want: 0},
+	}
+	for _, tt := range tests {
+		if got := m.at(tt.at); got != tt.want {
+			t.Errorf("at(%v) = %v, want %v", tt.at, got, tt.want)
+		}
+	}
+
+}
+
+func TestRasterize(t *testing.T) {
+	for _, variant := range []Name{Marble, Bauhaus, Beam, Pixel, Ring, Sunset} {
+		t.Run(variant.String(), func(t *testing.T) {
+
+			round, err := New("Mary Baker", Variant(variant))
+			if err != nil {
+				t.Errorf("New() error = %v", err)
+				return
+			}
+
+			img, err := rasterize(round, 64)
+			if err != nil {
+				t.Errorf("rasterize() error = %v", err)
+				return
+			}
+
+			if _, _, _, a := img.At(0, 0).RGBA(); a != 0 {
+				t.Errorf("%s corner is not masked", variant)
+			}
+
+			if _, _, _, a := img.At(32, 32).RGBA(); a != 0xffff {
+				t.Errorf("%s center is not opaque", variant)
+			}
+
+			square, _ := New("Mary Baker", Variant(variant), Square())
+
+			img, err = rasterize(square, 64)
+			if err != nil {
+				t.Errorf("rasterize() error = %v", err)
+				return
+			}
+
+			if _, _, _, a := img.At(0, 0).RGBA(); a != 0xffff {
+				t.Errorf("%s square corner is masked", variant)
+			}
+
+		})
+	}
+}
+
+func TestEncodeGIF(t *testing.T) {
+	for _, variant := range []Name{Bauhaus, Beam, Ring} {
+		t.Run(variant.String(), func(t *testing.T) {
+
+			b := bytes.NewBuffer(nil)
+
+			if err := EncodeGIF(b, "Mary Baker", 32, Variant(variant)); err != nil {
+				t.Errorf("EncodeGIF() error = %v", err)
+				return
+			}
+
+			g, err := gif.DecodeAll(b)
+			if err != nil {
+				t.Errorf("unable to decode: %v", err)
+				return
+			}
+
+			c, _ := build("Mary Baker", Variant(variant), Animate())
+
+			if want := max(int(c.loop()*framesPerSecond), 1); len(g.Image) != want {
+				t.Errorf("%s has %d frames, want %d", variant, len(g.Image), want)
+			}
+
+			if len(g.Image) > 1 && g.LoopCount != 0 {
+				t.Errorf("%s does not loop forever", variant)
+			}
+
+			if g.Config.Width != 32 || g.Config.Height != 32 {
+				t.Errorf("%s is %dx%d", variant, g.Config.Width, g.Config.Height)
+			}
+
+		})
+	}
+
+	// A sleeping face has no eyes to blink, so it is a still image.
+	if c, _ := build("Mary Baker", Variant(Beam), Expression(Sleeping), Blink()); c.loop() != 0 {
+		t.Errorf("sleeping Beam loops every %vs", c.loop())
+	}
+
+	sleeping := bytes.NewBuffer(nil)
+
+	if err := EncodeGIF(sleeping, "Mary Baker", 32, Variant(Beam), Expression(Sleeping), Blink()); err != nil {
+		t.Errorf("EncodeGIF() error = %v", err)
+	} else if g, err := gif.DecodeAll(sleeping); err != nil || len(g.Image) != 1 {
+		t.Errorf("sleeping Beam did not encode a single frame: %v", err)
+	}
+
+	if c, _ := build("Mary Baker", Variant(Beam), Expression(Winking), Blink()); c.loop() == 0 {
+		t.Errorf("winking Beam does not loop")
+	}
+
+	if err := EncodeGIF(io.Discard, "Mary Baker", 0); !errors.Is(err, ErrInvalidPixels) {
+		t.Errorf("zero pixels returned %v", err)
+	}
+}
+
+func TestEncodeAPNG(t *testing.T) {
+
+	b := bytes.NewBuffer(nil)
+
+	if err := EncodeAPNG(b, "Mary Baker", 32, Variant(Beam)); err != nil {
+		t.Errorf("EncodeAPNG() error = %v", err)
+		return
+	}
+
+	raw := b.Bytes()
+
+	img, err := png.Decode(bytes.NewReader(raw))
+	if err != nil {
+		t.Errorf("first frame can not be decoded: %v", err)
+		return
+	}
+
+	if img.Bounds().Dx() != 32 {
+		t.Errorf("image is %d pixels wide", img.Bounds().Dx())
+	}
+
+	c, _ := build("Mary Baker", Variant(Beam), Animate())
+	frames := int(c.loop() * framesPerSecond)
+
+	if i := bytes.Index(raw, []byte("acTL")); i < 0 || int(binary.BigEndian.Uint32(raw[i+4:])) != frames {
+		t.Errorf("animation control chunk is missing or does not count %d frames", frames)
+	}
+
+	if n := bytes.Count(raw, []byte("fdAT")); n != frames-1 {
+		t.Errorf("found %d frame data chunks, want %d", n, frames-1)
+	}
+
+}
+
+func TestEncodeICO(t *testing.T) {
+
+	b := bytes.NewBuffer(nil)
+
+	if err := EncodeICO(b, "Mary Baker", Variant(Beam)); err != nil {
+		t.Errorf("EncodeICO() error = %v", err)
+		return
+	}
+
+	raw := b.Bytes()
+
+	if binary.LittleEndian.Uint16(raw[2:]) != 1 || int(binary.LittleEndian.Uint16(raw[4:])) != len(icoSizes) {
+		t.Errorf("invalid icon directory % x", raw[:6])
+		return
+	}
+
+	for i, size := range icoSizes {
+
+		var (
+			entry  = raw[6+16*i:]
+			length = binary.LittleEndian.Uint32(entry[8:])
+			offset = binary.LittleEndian.Uint32(entry[12:])
+		)
+
+		img, err := png.Decode(bytes.NewReader(raw[offset : offset+length]))
+		if err != nil {
+			t.Errorf("image %d can not be decoded: %v", i, err)
+			continue
+		}
+
+		if img.Bounds().Dx() != size || int(entry[0]) != size%256 {
+			t.Errorf("image %d is %d pixels and listed as %d, want %d", i, img.Bounds().Dx(), entry[0], size)
+		}
+	}
+
+}
+
+func TestEncodeICNS(t *testing.T) {
+
+	b := bytes.NewBuffer(nil)
+
+	if err := EncodeICNS(b, "Mary Baker", Variant(Pixel), Square()); err != nil {
+		t.Errorf("EncodeICNS() error = %v", err)
+		return
+	}
+
+	raw := b.Bytes()
+
+	if string(raw[:4]) != "icns" || int(binary.BigEndian.Uint32(raw[4:])) != len(raw) {
+		t.Errorf("invalid header % x", raw[:8])
+		return
+	}
+
+	var kinds []string
+
+	for rest := raw[8:]; len(rest) >= 8; {
+
+		length := binary.BigEndian.Uint32(rest[4:])
+
+		if _, err := png.Decode(bytes.NewReader(rest[8:length])); err != nil {
+			t.Errorf("%s can not be decoded: %v", rest[:4], err)
+		}
+
+		kinds = append(kinds, string(rest[:4]))
+		rest = rest[length:]
+	}
+
+	if len(kinds) != len(icnsTypes) {
+		t.Errorf("found %v", kinds)
+	}
+
+}
+
+func TestManifestIcons(t *testing.T) {
+
+	icons, err := ManifestIcons("Mary Baker", "/static/icons", Square())
+	if err != nil {
+		t.Errorf("ManifestIcons() error = %v", err)
+		return
+	}
+
+	got, _ := json.Marshal(icons)
+	want := `[{"src":"/static/icons/icon-192.png","sizes":"192x192","type":"image/png","purpose":"any maskable"},{"src":"/static/icons/icon-512.png","sizes":"512x512","type":"image/png","purpose":"any maskable"}]`
+
+	if string(got) != want {
+		t.Errorf("ManifestIcons()\ng: %s\nw: %s", got, want)
+	}
+
+	for _, icon := range icons {
+		if img, err := png.Decode(bytes.NewReader(icon.Data)); err != nil || icon.Sizes != fmt.Sprintf("%dx%d", img.Bounds().Dx(), img.Bounds().Dy()) {
+			t.Errorf("%s does not hold a %s image", icon.Src, icon.Sizes)
+		}
+	}
+
+}
+
+func TestDataURI(t *testing.T) {
+
+	avatar := Render("Mary O'Baker & Co #1", Title(), Variant(Sunset), Animate())
+	raw := avatar.String()
+
+	uri := avatar.DataURI()
+
+	if !strings.HasPrefix(uri, "data:image/svg+xml,") {
+		t.Errorf("DataURI() has the wrong prefix: %s", uri)
+	}
+
+	if strings.ContainsAny(strings.TrimPrefix(uri, "data:image/svg+xml,"), "\"#&<>\\") {
+		t.Errorf("DataURI() has unescaped characters: %s", uri)
+	}
+
+	if strings.Count(uri, "'") != strings.Count(raw, `"`) {
+		t.Errorf("DataURI() did not swap every double quote")
+	}
+
+	// An ampersand would start an entity when the URI is put in an attribute.
+	if !strings.Contains(uri, "%26amp;") {
+		t.Errorf("DataURI() did not escape the ampersand: %s", uri)
+	}
+
+	decoded, err := url.PathUnescape(strings.ReplaceAll(strings.TrimPrefix(uri, "data:image/svg+xml,"), "'", `"`))
+	if err != nil || strings.ReplaceAll(decoded, `"`, "'") != strings.ReplaceAll(raw, `"`, "'") {
+		t.Errorf("DataURI() does not decode to the avatar: %v", err)
+	}
+
+	b64, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(avatar.Base64DataURI(), "data:image/svg+xml;base64,"))
+	if err != nil || string(b64) != raw {
+		t.Errorf("Base64DataURI() does not decode to the avatar: %v", err)
+	}
+
+	if css := avatar.CSSURL(); css != `url("`+uri+`")` {
+		t.Errorf("CSSURL() = %s", css)
+	}
+
+	failed := Render("Mary Baker", returnErr("testing"))
+
+	if failed.DataURI() != "" || failed.Base64DataURI() != "" || failed.CSSURL() != "" {
+		t.Errorf("an Avatar with an error returned data")
+	}
+
+}
+
+func TestFuncMap(t *testing.T) {
+
+	tmpl, err := template.New("page").Funcs(FuncMap()).Parse(`<div>{{ avatar .Name "beam" 64 }}</div><img src="{{ avatarURL .Name "ring" }}" alt="">{{ .Avatar.HTML }}`)
+	if err != nil {
+		t.Errorf("unable to parse template: %v", err)
+		return
+	}
+
+	b := bytes.NewBuffer(nil)
+
+	data := struct {
+		Name   string
+		Avatar Avatar
+	}{
+		Name:   `Mary <script>alert("Baker")</script>`,
+		Avatar: Render("Mary Baker", Variant(Pixel)),
+	}
+
+	if err := tmpl.Execute(b, data); err != nil {
+		t.Errorf("unable to execute template: %v", err)
+		return
+	}
+
+	got := b.String()
+
+	beam, _ := New(data.Name, Variant(Beam), Size(64, "px"))
+	ring := Render(data.Name, Variant(Ring)).DataURI()
+
+	if !strings.Contains(got, "<div>"+beam+"</div>") {
+		t.Errorf("avatar was escaped\n%s", got)
+	}
+
+	// html/template normalizes URLs, which only changes how the data URI is encoded.
+	_, src, _ := strings.Cut(got, `src="`)
+	src, _, _ = strings.Cut(src, `"`)
+
+	want, _ := url.PathUnescape(ring)
+	if src, err := url.PathUnescape(html.UnescapeString(src)); err != nil || src != want {
+		t.Errorf("avatarURL was escaped\n%s", got)
+	}
+
+	if !strings.HasSuffix(got, data.Avatar.String()) {
+		t.Errorf("Avatar.HTML was escaped\n%s", got)
+	}
+
+	if strings.Contains(got, "<script>") {
+		t.Errorf("name was not escaped\n%s", got)
+	}
+
+	titled, _ := New(data.Name, Title(), Variant(Sunset), Classes("a&b"))
+	if titled == "" || strings.Contains(titled, "<script>") || !strings.Contains(titled, `class="a&amp;b"`) {
+		t.Errorf("title or classes were not escaped\n%s", titled)
+	}
+
+	if _, err := New(data.Name, Classes(`"><script>`)); !errors.Is(err, ErrInvalidClass) {
+		t.Errorf("a class with markup returned %v", err)
+	}
+
+	if err := tmpl.Execute(io.Discard, struct {
+		Name   string
+		Avatar Avatar
+	}{Name: ""}); err == nil {
+		t.Errorf("an empty name did not return an error")
+	}
+
+	if _, err := templateOptions([]any{"cubist"}); !errors.Is(err, ErrInvalidVariant) {
+		t.Errorf("invalid variant returned %v", err)
+	}
+
+}
+
+func TestImage(t *testing.T) {
+
+	round, err := NewImage("Mary Baker", 48, Variant(Bauhaus))
+	if err != nil {
+		t.Errorf("NewImage() error = %v", err)
+		return
+	}
+
+	if round.Bounds() != image.Rect(0, 0, 48, 48) || round.img != nil {
+		t.Errorf("Bounds() = %v, rasterized %v", round.Bounds(), round.img != nil)
+	}
+
+	square, err := Render("Mary Baker", Variant(Bauhaus), Square()).Image(48)
+	if err != nil {
+		t.Errorf("Image() error = %v", err)
+		return
+	}
+
+	for _, tt := range []struct {
+		name   string
+		img    image.Image
+		corner color.RGBA
+	}{
+		{name: "round", img: round, corner: color.RGBA{255, 255, 255, 255}},
+		{name: "square", img: square, corner: color.RGBA{0x0A, 0x03, 0x10, 255}},
+	} {
+
+		card := image.NewRGBA(image.Rect(0, 0, 64, 64))
+		draw.Draw(card, card.Bounds(), image.White, image.Point{}, draw.Src)
+		draw.Draw(card, image.Rect(8, 8, 56, 56), tt.img, image.Point{}, draw.Over)
+
+		if got := card.RGBAAt(8, 8); got != tt.corner {
+			t.Errorf("%s corner is %v, want %v", tt.name, got, tt.corner)
+		}
+
+		if got := card.RGBAAt(4, 4); got != (color.RGBA{255, 255, 255, 255}) {
+			t.Errorf("%s was drawn outside of its bounds", tt.name)
+		}
+	}
+
+	if _, err := NewImage("Mary Baker", 0); !errors.Is(err, ErrInvalidPixels) {
+		t.Errorf("zero pixels returned %v", err)
+	}
+
+	if _, err := NewImage("", 48); !errors.Is(err, ErrEmptyName) {
+		t.Errorf("empty name returned %v", err)
+	}
+
+}
+
+func TestGroup(t *testing.T) {
+
+	var (
+		names = []string{"Mary Baker", "Amelia Earhart", "Mary Roebling", "Sarah Winnemucca", "Margaret Brent"}
+		ids   = regexp.MustCompile(`id="([^"]+)"`)
+		refs  = regexp.MustCompile(`url\(#([^)]+)\)`)
+	)
+
+	for _, layout := range []Layout{Stack, Grid, Pie} {
+		for _, variant := range []Name{Marble, Sunset, Beam} {
+
+			svg, err := Group(names, layout, Variant(variant), Animate())
+			other, _ := Group(names[1:], layout, Variant(variant), Animate())
+			squared, _ := Group(names, layout, Variant(variant), Animate(), Square())
+			if err != nil {
+				t.Errorf("Group(%s, %s) error = %v", layout, variant, err)
+				continue
+			}
+
+			if strings.Contains(svg, "Margaret Brent") || strings.Count(svg, "<svg") != 5 {
+				t.Errorf("Group(%s, %s) should nest the first four members", layout, variant)
+			}
+
+			defined := map[string]bool{}
+
+			for _, m := range ids.FindAllStringSubmatch(svg, -1) {
+
+				if defined[m[1]] {
+					t.Errorf("Group(%s, %s) repeats the id %q", layout, variant, m[1])
+				}
+
+				defined[m[1]] = true
+			}
+
+			// Groups with other members or shapes can share a page with this one.
+			for _, m := range ids.FindAllStringSubmatch(other+squared, -1) {
+				if defined[m[1]] {
+					t.Errorf("Group(%s, %s) shares the id %q with another group", layout, variant, m[1])
+				}
+			}
+
+			for _, m := range refs.FindAllStringSubmatch(svg, -1) {
+				if !defined[m[1]] {
+					t.Errorf("Group(%s, %s) references the missing id %q", layout, variant, m[1])
+				}
+			}
+
+			if _, err := rasterize(svg, 32); err != nil {
+				t.Errorf("Group(%s, %s) does not rasterize: %v", layout, variant, err)
+			}
+		}
+	}
+
+	single, err := Group(names[:1], Grid)
+	if err != nil {
+		t.Errorf("Group() error = %v", err)
+		return
+	}
+
+	want, err := getInternals(Render(names[0]).String())
+	if err != nil {
+		t.Error(err)
+	}
+
+	// The member keeps its content, but is namespaced and no longer rounded.
+	if !strings.Contains(regexp.MustCompile(`g\d+-grid-m0-`).ReplaceAllString(single, ""), want) {
+		t.Errorf("Group() does not contain the avatar of its member: %s", single)
+	}
+
+	if _, err := Group(nil, Stack); !errors.Is(err, ErrEmptyName) {
+		t.Errorf("no names returned %v", err)
+	}
+
+	if _, err := Group(names, Layout{"spiral"}); !errors.Is(err, ErrInvalidLayout) {
+		t.Errorf("invalid layout returned %v", err)
+	}
+
+}
+
+func TestBadge(t *testing.T) {
+
+	for _, variant := range []Name{Marble, Beam, Ring, Sunset, Pixel, Bauhaus} {
+
+		svg, err := New("Mary Baker", Variant(variant), Badge(BottomRight, "#22C55E", "3"), BadgeOutline("#FFFFFF"))
+		if err != nil {
+			t.Errorf("New(%s) error = %v", variant, err)
+			continue
+		}
+
+		// The badge follows the masked group, so the mask doesn't clip it.
+		masked := strings.Index(svg, `</g><rect`)
+		if masked < 0 || !strings.Contains(svg[masked:], `fill="#22C55E"`) || !strings.Contains(svg[masked:], `>3</text>`) {
+			t.Errorf("New(%s) should draw the badge after the mask: %s", variant, svg)
+		}
+
+		img, err := rasterize(svg, 100)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+
+		// The corner of a round avatar is empty, apart from the outline of the badge.
+		if got := img.RGBAAt(99, 99); got != (color.RGBA{}) {
+			t.Errorf("New(%s) corner = %v, want transparent", variant, got)
+		}
+
+		if got := img.RGBAAt(98, 80); got != (color.RGBA{255, 255, 255, 255}) {
+			t.Errorf("New(%s) outline = %v, want white", variant, got)
+		}
+
+		if got := img.RGBAAt(80, 80); got != (color.RGBA{0x22, 0xC5, 0x5E, 255}) {
+			t.Errorf("New(%s) badge = %v, want green", variant, got)
+		}
+	}
+
+	pill, err := New("Mary Baker", Badge(TopLeft, "#FFB238", "99+"))
+	if err != nil {
+		t.Errorf("New() error = %v", err)
+		return
+	}
+
+	// Dark text on a light badge that is wider than it is tall.
+	if !strings.Contains(pill, `<rect x="0" y="0" width="41.28" height="24" rx="12" fill="#FFB238">`) || !strings.Contains(pill, `fill="#000000"`) {
+		t.Errorf("New() pill = %s", pill)
+	}
+
+	if _, err := New("Mary Baker", Badge(Corner{"middle"}, "#22C55E", "")); !errors.Is(err, ErrInvalidCorner) {
+		t.Errorf("invalid corner returned %v", err)
+	}
+
+	// Without a Badge there is nothing to outline.
+	outlined, _ := New("Mary Baker", BadgeOutline("#FFFFFF"))
+	plain, _ := New("Mary Baker")
+
+	if outlined != plain {
+		t.Errorf("BadgeOutline() without Badge changed the avatar")
+	}
+
+}
+
+func TestShape(t *testing.T) {
+
+	tests := []struct {
+		name string
+		mask Mask
+		want string
+	}{
+		{name: "circle", mask: Circle, want: `<rect width="80" height="80" rx="160" fill="#FFFFFF"></rect>`},
+		{name: "rounded", mask: RoundedSquare(25), want: `<rect width="80" height="80" rx="20" fill="#FFFFFF"></rect>`},
+		{name: "squircle", mask: Squircle, want: `<path d="M50 0C10 0 0 10 0 50s10 50 50 50 50-10 50-50S90 0 50 0z" transform="scale(0.8)" fill="#FFFFFF"></path>`},
+		{name: "hexagon", mask: Hexagon, want: `transform="scale(0.8)" fill="#FFFFFF"></path></mask>`},
+		{name: "custom", mask: CustomShape("M0 0H100L50 100z"), want: `<path d="M0 0H100L50 100z" transform="scale(0.8)"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			svg, err := New("Mary Baker", Variant(Bauhaus), Shape(tt.mask))
+			if err != nil {
+				t.Errorf("New() error = %v", err)
+				return
+			}
+
+			if !strings.Contains(svg, tt.want) {
+				t.Errorf("New() = %s, want mask %s", svg, tt.want)
+			}
+
+			if _, err := rasterize(svg, 16); err != nil {
+				t.Errorf("rasterize() error = %v", err)
+			}
+		})
+	}
+
+	// A triangle pointing down leaves the bottom corners empty.
+	img, err := NewImage("Mary Baker", 40, Variant(Bauhaus), Shape(CustomShape("M0 0H100L50 100z")))
+	if err != nil {
+		t.Errorf("NewImage() error = %v", err)
+		return
+	}
+
+	if _, _, _, a := img.At(2, 38).RGBA(); a != 0 {
+		t.Errorf("corner of the triangle is not transparent")
+	}
+
+	if _, _, _, a := img.At(2, 2).RGBA(); a == 0 {
+		t.Errorf("top of the triangle is transparent")
+	}
+
+	// The last of Square and Shape wins.
+	squared, _ := New("Mary Baker", Shape(Hexagon), Square())
+	shaped, _ := New("Mary Baker", Square(), Shape(Hexagon))
+
+	if !strings.Contains(squared, `<rect width="80" height="80" fill="#FFFFFF">`) || !strings.Contains(shaped, `scale(0.8)" fill="#FFFFFF"></path>`) {
+		t.Errorf("Square() and Shape() should override each other")
+	}
+
+	// Avatars inlined in one page keep their own outlines.
+	ids := map[string]Mask{}
+
+	for _, mask := range []Mask{Circle, RoundedSquare(25), Squircle, Hexagon, CustomShape("M0 0H100L50 100z")} {
+
+		svg, _ := New("Mary Baker", Variant(Bauhaus), Shape(mask))
+		id := svg[strings.Index(svg, `<mask id="`)+10:]
+		id = id[:strings.IndexByte(id, '"')]
+
+		if other, ok := ids[id]; ok {
+			t.Errorf("Shape(%v) and Shape(%v) share the mask id %s", mask, other, id)
+		}
+
+		ids[id] = mask
+	}
+
+	if plain, _ := New("Mary Baker", Variant(Bauhaus)); !strings.Contains(plain, `<mask id="avatar_bauhaus" `) {
+		t.Errorf("New() mask id changed for the default circle")
+	}
+
+	for _, mask := range []Mask{{mask: "star"}, RoundedSquare(60), CustomShape(""), CustomShape("hello")} {
+		if _, err := New("Mary Baker", Shape(mask)); !errors.Is(err, ErrInvalidShape) {
+			t.Errorf("Shape(%v) returned %v", mask, err)
+		}
+	}
+
+}
+
+func TestDecorations(t *testing.T) {
+
+	plain, err := NewImage("Mary Baker", 100, Variant(Bauhaus), Square())
+	if err != nil {
+		t.Errorf("NewImage() error = %v", err)
+		return
+	}
+
+	border, err := NewImage("Mary Baker", 100, Variant(Bauhaus), Square(), Border("#FFFFFF", 5))
+	if err != nil {
+		t.Errorf("NewImage() error = %v", err)
+		return
+	}
+
+	// The border is drawn over the edge of the avatar, which keeps its size.
+	if got := border.At(2, 50); got != (color.RGBA{255, 255, 255, 255}) {
+		t.Errorf("border = %v, want white", got)
+	}
+
+	if border.At(50, 50) != plain.At(50, 50) {
+		t.Errorf("border changed the middle of the avatar")
+	}
+
+	ring, err := NewImage("Mary Baker", 100, Variant(Bauhaus), Square(), StoryRing(4, 4))
+	if err != nil {
+		t.Errorf("NewImage() error = %v", err)
+		return
+	}
+
+	// From the edge: the ring, then the gap, then the shrunken avatar.
+	if _, _, _, a := ring.At(2, 50).RGBA(); a == 0 {
+		t.Errorf("ring is missing")
+	}
+
+	if _, _, _, a := ring.At(6, 50).RGBA(); a != 0 {
+		t.Errorf("gap is not transparent")
+	}
+
+	if _, _, _, a := ring.At(10, 50).RGBA(); a == 0 {
+		t.Errorf("avatar is missing inside the ring")
+	}
+
+	shadow, err := Render("Mary Baker", Variant(Beam), Shadow(), GradientBorder(2), StoryRing(3, 2)).Image(100)
+	if err != nil {
+		t.Errorf("Image() error = %v", err)
+		return
+	}
+
+	// The shadow falls below the avatar, where it would otherwise be empty.
+	if _, _, _, a := shadow.At(50, 95).RGBA(); a == 0 {
+		t.Errorf("shadow is missing")
+	}
+
+	svg, _ := New("Mary Baker", Variant(Beam), Shadow(), GradientBorder(2), StoryRing(3, 2))
+
+	for _, want := range []string{`<g filter="url(#shadow_36)">`, `stroke="url(#border_gradient_`, `stroke="url(#ring_gradient_`, `<feDropShadow`} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("New() = %s, want %s", svg, want)
+		}
+	}
+
+	for _, opt := range []Option{Border("#FFFFFF", 0), Border("#FFFFFF", 51), GradientBorder(-1), StoryRing(0, 2), StoryRing(25, 25)} {
+		if _, err := New("Mary Baker", opt); !errors.Is(err, ErrInvalidWidth) {
+			t.Errorf("invalid width returned %v", err)
+		}
+	}
+
+}
+
+func TestTransforms(t *testing.T) {
+
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{name: "rotate", opts: []Option{Rotate(90)}, want: `<g transform="translate(40 40) rotate(90) translate(-40 -40)">`},
+		{name: "flip", opts: []Option{FlipHorizontal()}, want: `<g transform="translate(40 40) scale(-1 1) translate(-40 -40)">`},
+		{name: "zoom", opts: []Option{Zoom(1.5), FlipVertical()}, want: `<g transform="translate(40 40) scale(1.5 -1.5) translate(-40 -40)">`},
+		{name: "padding", opts: []Option{Padding(10), Background("#FFFFFF")}, want: `<rect width="80" height="80" fill="#FFFFFF"></rect><g transform="translate(40 40) scale(0.8 0.8) translate(-40 -40)"><g mask="url(#avatar_bauhaus)">`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			svg, err := New("Mary Baker", append(tt.opts, Variant(Bauhaus))...)
+			if err != nil {
+				t.Errorf("New() error = %v", err)
+				return
+			}
+
+			if !strings.Contains(svg, tt.want) {
+				t.Errorf("New() = %s, want %s", svg, tt.want)
+			}
+		})
+	}
+
+	// Mirroring twice across the same axis, or turning all the way, changes nothing.
+	var (
+		plain, _   = NewImage("Mary Baker", 40, Variant(Pixel))
+		flipped, _ = NewImage("Mary Baker", 40, Variant(Pixel), FlipHorizontal())
+		turned, _  = NewImage("Mary Baker", 40, Variant(Pixel), Rotate(180), FlipHorizontal(), FlipVertical())
+	)
+
+	for _, p := range []image.Point{{10, 12}, {30, 25}, {18, 33}} {
+
+		if flipped.At(p.X, p.Y) != plain.At(39-p.X, p.Y) {
+			t.Errorf("flipped pixel %v = %v, want %v", p, flipped.At(p.X, p.Y), plain.At(39-p.X, p.Y))
+		}
+
+		if turned.At(p.X, p.Y) != plain.At(p.X, p.Y) {
+			t.Errorf("turned pixel %v = %v, want %v", p, turned.At(p.X, p.Y), plain.At(p.X, p.Y))
+		}
+	}
+
+	for _, opt := range []Option{Padding(-1), Padding(50), Padding(math.NaN()), Zoom(0), Zoom(math.Inf(1)), Zoom(math.NaN()), Rotate(math.Inf(1)), Rotate(math.Inf(-1)), Rotate(math.NaN())} {
+		if _, err := New("Mary Baker", opt); !errors.Is(err, ErrInvalidTransform) {
+			t.Errorf("invalid transform returned %v", err)
+		}
+	}
+
+}
+
+func TestAccessibility(t *testing.T) {
+
+	plain, _ := New("mary@example.com")
+
+	if !strings.HasPrefix(plain, `<svg viewBox="0 0 80 80" fill="none" role="img" xmlns="http://www.w3.org/2000/svg" width="40" height="40">`) {
+		t.Errorf("New() changed the default svg element: %s", plain)
+	}
+
+	labelled, err := New("mary@example.com", Label("Mary Baker"), Description("Avatar of <Mary>"), Lang("en-GB"))
+	if err != nil {
+		t.Errorf("New() error = %v", err)
+		return
+	}
+
+	var doc struct {
+		Role       string `xml:"role,attr"`
+		Lang       string `xml:"lang,attr"`
+		LabelledBy string `xml:"aria-labelledby,attr"`
+		Describe   string `xml:"aria-describedby,attr"`
+		Title      struct {
+			ID   string `xml:"id,attr"`
+			Text string `xml:",chardata"`
+		} `xml:"title"`
+		Desc struct {
+			ID   string `xml:"id,attr"`
+			Text string `xml:",chardata"`
+		} `xml:"desc"`
+	}
+
+	if err := xml.Unmarshal([]byte(labelled), &doc); err != nil {
+		t.Errorf("xml.Unmarshal() error = %v", err)
+		return
+	}
+
+	if doc.Role != "img" || doc.Lang != "en-GB" || doc.Title.Text != "Mary Baker" || doc.Desc.Text != "Avatar of <Mary>" {
+		t.Errorf("New() = %s", labelled)
+	}
+
+	if doc.LabelledBy == "" || doc.LabelledBy != doc.Title.ID || doc.Describe == "" || doc.Describe != doc.Desc.ID {
+		t.Errorf("aria attributes are not wired to the title and description: %s", labelled)
+	}
+
+	if strings.Contains(labelled, "example.com") {
+		t.Errorf("the hashed name leaked into the markup: %s", labelled)
+	}
+
+	other, _ := New("john@example.com", Label("Mary Baker"))
+	if strings.Contains(other, doc.Title.ID) {
+		t.Errorf("avatars with the same label share the title id %s", doc.Title.ID)
+	}
+
+	hidden, _ := New("Mary Baker", Decorative(), Title(), Label("Mary"), Description("Mary"))
+
+	if !strings.Contains(hidden, `aria-hidden="true"`) || strings.Contains(hidden, `role=`) || strings.Contains(hidden, `<title`) || strings.Contains(hidden, `<desc`) {
+		t.Errorf("Decorative() = %s", hidden)
+	}
+
+	for _, tag := range []string{"", "en_GB", "-en", `en"`} {
+		if _, err := New("Mary Baker", Lang(tag)); !errors.Is(err, ErrInvalidLang) {
+			t.Errorf("Lang(%q) returned %v", tag, err)
+		}
+	}
+
+}
+
+func TestNormalize(t *testing.T) {
+
+	want, _ := New("mary baker", Normalize())
+
+	for _, name := range []string{"Mary Baker", " mary  baker ", "MARY\tBAKER", "Mary BaKer"} {
+
+		got, err := New(name, Normalize())
+		if err != nil {
+			t.Errorf("New(%q) error = %v", name, err)
+			continue
+		}
+
+		if got != want {
+			t.Errorf("New(%q) differs from New(%q)", name, "mary baker")
+		}
+	}
+
+	// Only the hash changes, the title shows the name as it was given.
+	titled, _ := New("  MARY BAKER ", Normalize(), Title())
+	if !strings.Contains(titled, ">  MARY BAKER </title>") {
+		t.Errorf("New() title = %s", titled)
+	}
+
+	plain, _ := New("MARY BAKER")
+	if plain == want {
+		t.Errorf("names are normalized without Normalize")
+	}
+
+	tests := []struct {
+		normalizer Normalizer
+		in, want   string
+	}{
+		{Trim, "  Mary  Baker ", "Mary  Baker"},
+		{CollapseSpace, "  Mary \n Baker ", "Mary Baker"},
+		{FoldCase, "ΣΑΣ Straße", "σασ straße"},
+		{CanonicalEmail, " Mary.Baker+News@Example.COM ", "mary.baker@example.com"},
+		{CanonicalEmail, "+tag@example.com", "+tag@example.com"},
+		{CanonicalEmail, "Mary Baker", "Mary Baker"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.normalizer(tt.in); got != tt.want {
+			t.Errorf("normalizer(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	// Custom normalizers run in order, after the ones before them.
+	email, _ := New("mary@example.com")
+	tagged, _ := New("MARY+avatars@example.com", Normalize(CanonicalEmail))
+
+	if email != tagged {
+		t.Errorf("CanonicalEmail did not give the same avatar")
+	}
+
+	if _, err := New("   ", Normalize()); !errors.Is(err, ErrEmptyName) {
+		t.Errorf("a blank name returned %v", err)
+	}
+
+}
+
+func TestPrivate(t *testing.T) {
+
+	names := []string{"mary.baker+news@example.com", "Mary Baker", "Amelia_Earhart"}
+
+	// Each name, along with the forms of it that variants could write.
+	leaks := func(name string) []string {
+
+		stripped := strings.Map(func(r rune) rune {
+			if r == ' ' || r == '.' || r == '+' || r == '@' {
+				return -1
+			}
+			return r
+		}, name)
+
+		return []string{name, strings.ToLower(name), html.EscapeString(name), stripped, strings.ToLower(stripped)}
+	}
+
+	extras := [][]Option{
+		{Title()},
+		{Title(), Animate(), Blink(), Badge(TopRight, "#FF005B", "2"), GradientBorder(3), StoryRing(3, 2), Shadow()},
+		{Title(), Normalize(CanonicalEmail, FoldCase), Description("An avatar"), Shape(Hexagon)},
+	}
+
+	for _, name := range names {
+		for _, variant := range []Name{Marble, Beam, Ring, Sunset, Pixel, Bauhaus} {
+			for _, extra := range extras {
+
+				opts := append([]Option{Private(), Variant(variant)}, extra...)
+
+				svg, err := New(name, opts...)
+				if err != nil {
+					t.Errorf("New(%q, %s) error = %v", name, variant, err)
+					continue
+				}
+
+				group, err := Group([]string{name, "Sarah Winnemucca"}, Pie, opts...)
+				if err != nil {
+					t.Errorf("Group(%q, %s) error = %v", name, variant, err)
+					continue
+				}
+
+				for _, leak := range leaks(name) {
+					if strings.Contains(svg, leak) || strings.Contains(group, leak) {
+						t.Errorf("New(%q, %s) leaked %q", name, variant, leak)
+					}
+				}
+			}
+		}
+	}
+
+	// Without Private, the sunset gradients are named after the name.
+	public, _ := New("Amelia_Earhart", Variant(Sunset))
+	private, _ := New("Amelia_Earhart", Variant(Sunset), Private())
+
+	if !strings.Contains(public, "Amelia_Earhart") || public == private {
+		t.Errorf("Private() should only change private avatars")
+	}
+
+	// The title can still be set explicitly.
+	labelled, _ := New("mary@example.com", Private(), Title(), Label("Mary"))
+
+	if !strings.Contains(labelled, ">Mary</title>") || strings.Contains(labelled, "example") {
+		t.Errorf("Private() with Label() = %s", labelled)
+	}
+
+}
+
+func TestMinify(t *testing.T) {
+
+	sets := map[string][]Option{
+		"plain":     nil,
+		"square":    {Square(), Title()},
+		"animated":  {Animate(), Blink()},
+		"decorated": {Shape(Hexagon), Badge(BottomRight, "#22C55E", "3"), BadgeOutline("#FFFFFF"), GradientBorder(3), StoryRing(3, 2), Shadow(), Padding(5), FlipVertical(), Label("Mary")},
+	}
+
+	for _, variant := range []Name{Marble, Beam, Ring, Sunset, Pixel, Bauhaus} {
+		for set, opts := range sets {
+
+			opts := append([]Option{Variant(variant)}, opts...)
+
+			original, err := New("Mary Baker", opts...)
+			if err != nil {
+				t.Errorf("New(%s, %s) error = %v", variant, set, err)
+				continue
+			}
+
+			minified, err := New("Mary Baker", append(opts, Minify())...)
+			if err != nil {
+				t.Errorf("New(%s, %s) with Minify() error = %v", variant, set, err)
+				continue
+			}
+
+			if len(minified) >= len(original) {
+				t.Errorf("Minify() did not shorten %s, %s", variant, set)
+			}
+
+			if strings.Contains(minified, "translate(0 0)") || strings.Contains(minified, "-0 ") || strings.Count(minified, "<defs>") > 1 {
+				t.Errorf("Minify() left redundant markup in %s, %s: %s", variant, set, minified)
+			}
+
+			want, _ := rasterize(original, 48)
+
+			got, err := rasterize(minified, 48)
+			if err != nil {
+				t.Errorf("rasterize() error = %v", err)
+				continue
+			}
+
+			changed := 0
+
+			for i := 0; i < len(got.Pix); i += 4 {
+				if !bytes.Equal(got.Pix[i:i+4], want.Pix[i:i+4]) {
+					changed++
+				}
+			}
+
+			// Merged squares lose the antialiased seams between neighbours of the same
+			// color, which show when they don't line up with the pixels.
+			allowed := 0
+			if variant == Pixel && set == "decorated" {
+				allowed = len(got.Pix) / 4 / 20
+			}
+
+			if changed > allowed {
+				t.Errorf("Minify() changed %d pixels of %s, %s", changed, variant, set)
+			}
+		}
+	}
+
+	// Squares of the same color become one path.
+	pixel, _ := New("Mary Baker", Variant(Pixel), Minify())
+
+	if strings.Count(pixel, "<rect") != 1 || strings.Count(pixel, "<path") != 5 {
+		t.Errorf("Minify() did not merge the pixels: %s", pixel)
+	}
+
+	// Ids name what they point at, so groups keep theirs apart.
+	group, err := Group([]string{"Mary Baker", "Amelia Earhart"}, Pie, Variant(Sunset), Minify())
+	if err != nil {
+		t.Errorf("Group() error = %v", err)
+		return
+	}
+
+	ids := regexp.MustCompile(` id="(i[0-9a-z]+)"`).FindAllStringSubmatch(group, -1)
+	if len(ids) == 0 || strings.Count(group, `url(#`) < len(ids) {
+		t.Errorf("Group() ids were not shortened: %s", group)
+	}
+
+	for _, id := range ids {
+		if !strings.Contains(group, "url(#"+id[1]+")") {
+			t.Errorf("Group() lost the references to %s", id[1])
+		}
+	}
+
+	rounded, _ := New("Mary Baker", Precision(0))
+
+	if !strings.Contains(rounded, `d="M32 59L50 71H73v-71H34L27 13l19 27L32 59z"`) || !strings.Contains(rounded, `scale(1)`) {
+		t.Errorf("Precision(0) = %s", rounded)
+	}
+
+	if _, err := New("Mary Baker", Precision(11)); !errors.Is(err, ErrInvalidPrecision) {
+		t.Errorf("invalid precision returned %v", err)
+	}
+
+}
+
+func TestSVGZ(t *testing.T) {
+
+	avatar := Render("Mary Baker", Variant(Beam), Minify())
+
+	first, err := avatar.SVGZ()
+	if err != nil {
+		t.Errorf("SVGZ() error = %v", err)
+		return
+	}
+
+	second := bytes.NewBuffer(nil)
+
+	if err := EncodeSVGZ(second, "Mary Baker", Variant(Beam), Minify()); err != nil {
+		t.Errorf("EncodeSVGZ() error = %v", err)
+		return
+	}
+
+	if !bytes.Equal(first, second.Bytes()) {
+		t.Errorf("SVGZ() is not deterministic")
+	}
+
+	z, err := gzip.NewReader(bytes.NewReader(first))
+	if err != nil {
+		t.Errorf("gzip.NewReader() error = %v", err)
+		return
+	}
+
+	svg, err := io.ReadAll(z)
+	if err != nil || string(svg) != avatar.String() {
+		t.Errorf("SVGZ() does not decompress to the avatar: %v", err)
+	}
+
+	if !z.ModTime.IsZero() || z.Name != "" {
+		t.Errorf("SVGZ() header has a time or name: %v %q", z.ModTime, z.Name)
+	}
+
+	dir := t.TempDir()
+
+	if err := Export(dir, map[string]string{"1": "Mary Baker", "2": "Amelia Earhart"}, Variant(Beam), Minify()); err != nil {
+		t.Errorf("Export() error = %v", err)
+		return
+	}
+
+	plain, err := os.ReadFile(filepath.Join(dir, "1.svg"))
+	if err != nil || string(plain) != avatar.String() {
+		t.Errorf("Export() wrote the wrong svg: %v", err)
+	}
+
+	compressed, err := os.ReadFile(filepath.Join(dir, "1.svgz"))
+	if err != nil || !bytes.Equal(compressed, first) {
+		t.Errorf("Export() wrote the wrong svgz: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "2.svgz")); err != nil {
+		t.Errorf("Export() skipped a file: %v", err)
+	}
+
+	for _, file := range []string{"", "..", "a/b", `a\b`} {
+		if err := Export(dir, map[string]string{file: "Mary Baker"}); !errors.Is(err, ErrInvalidFile) {
+			t.Errorf("Export(%q) returned %v", file, err)
+		}
+	}
+
+	if err := Export(dir, map[string]string{"3": ""}); !errors.Is(err, ErrEmptyName) {
+		t.Errorf("Export() of an empty name returned %v", err)
+	}
+
+	partial := t.TempDir()
+
+	for _, files := range []map[string]string{{"a": "Mary Baker", "z/z": "Amelia Earhart"}, {"a": "Mary Baker", "z": ""}} {
+
+		if err := Export(partial, files); err == nil {
+			t.Errorf("Export(%v) did not return an error", files)
+		}
+
+		if entries, _ := os.ReadDir(partial); len(entries) > 0 {
+			t.Errorf("Export(%v) wrote %d files before failing", files, len(entries))
+		}
+	}
+
+}
+
+func TestCache(t *testing.T) {
+
+	memory := NewMemoryCache(2, 0)
+
+	memory.Set("a", "1")
+	memory.Set("b", "2")
+	memory.Get("a")
+	memory.Set("c", "3")
+
+	if _, ok := memory.Get("b"); ok {
+		t.Errorf("MemoryCache kept the least recently used entry")
+	}
+
+	if svg, ok := memory.Get("a"); !ok || svg != "1" {
+		t.Errorf("MemoryCache.Get() = %q, %v", svg, ok)
+	}
+
+	bounded := NewMemoryCache(0, 5)
+
+	bounded.Set("a", "123")
+	bounded.Set("b", "456")
+	bounded.Set("c", "123456")
+
+	if bounded.Len() != 1 {
+		t.Errorf("MemoryCache went over its bytes: %d entries", bounded.Len())
+	}
+
+	disk := NewDiskCache(t.TempDir())
+
+	disk.Set("abc123", "<svg></svg>")
+	disk.Set("../escape", "<svg></svg>")
+
+	if svg, ok := disk.Get("abc123"); !ok || svg != "<svg></svg>" {
+		t.Errorf("DiskCache.Get() = %q, %v", svg, ok)
+	}
+
+	if _, ok := disk.Get("../escape"); ok {
+		t.Errorf("DiskCache stored a key outside of its directory")
+	}
+
+	for _, cache := range []Cache{NewMemoryCache(0, 0), NewDiskCache(t.TempDir())} {
+
+		g := NewGenerator(cache, Variant(Beam))
+
+		first, err := g.New("Mary Baker", Size(80, "px"))
+		if err != nil {
+			t.Errorf("Generator.New() error = %v", err)
+			continue
+		}
+
+		want, _ := New("Mary Baker", Variant(Beam), Size(80, "px"))
+
+		if first != want {
+			t.Errorf("Generator.New() = %v, want %v", first, want)
+		}
+
+		c, _ := build("Mary Baker", Variant(Beam), Size(80, "px"))
+
+		cache.Set(c.fingerprint(), "cached")
+
+		if svg, _ := g.New("Mary Baker", Size(80, "px")); svg != "cached" {
+			t.Errorf("Generator.New() skipped the cache")
+		}
+
+		if svg, _ := g.New("Mary Baker", Size(40, "px")); svg == "cached" {
+			t.Errorf("Generator.New() ignored an option in the key")
+		}
+	}
+
+	g := NewGenerator(NewMemoryCache(10, 0), Variant(Ring))
+
+	rec := httptest.NewRecorder()
+	g.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/avatars/Mary%20Baker.svg", nil))
+
+	want, _ := New("Mary Baker", Variant(Ring))
+
+	if rec.Code != http.StatusOK || rec.Body.String() != want || rec.Header().Get("Content-Type") != "image/svg+xml" {
+		t.Errorf("ServeHTTP() = %d %q", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	g.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("ServeHTTP() without a name = %d", rec.Code)
+	}
+
+}
+
+func TestFingerprint(t *testing.T) {
+
+	first, err := Fingerprint("Mary Baker", Variant(Beam), Size(80, "px"), Colors("#000000", "#111111", "#222222", "#333333", "#444444"))
+	if err != nil {
+		t.Errorf("Fingerprint() error = %v", err)
+		return
+	}
+
+	if !strings.HasPrefix(first, fingerprintVersion+"-") {
+		t.Errorf("Fingerprint() = %q is not versioned", first)
+	}
+
+	same := [][]Option{
+		{Colors("#000000", "#111111", "#222222", "#333333", "#444444"), Size(80, "PX"), Variant(Beam)},
+		{Variant(Pixel), Variant(Beam), Size(80, "px"), Colors("#000000", "#111111", "#222222", "#333333", "#444444")},
+	}
+
+	for _, opts := range same {
+		if got, _ := Fingerprint("Mary Baker", opts...); got != first {
+			t.Errorf("Fingerprint() = %q, want %q", got, first)
+		}
+	}
+
+	different := [][]Option{
+		{Variant(Beam), Size(80, "px")},
+		{Variant(Beam), Size(80, "px"), Colors("#000000", "#111111", "#222222", "#333333", "#444444"), Square()},
+		{Variant(Beam), Size(80, "px"), Colors("#000000", "#111111", "#222222", "#333333", "#444444"), Title()},
+		{Variant(Beam), Size(80, "px"), Colors("#000000", "#111111", "#222222", "#333333", "#444444"), Classes("avatar")},
+	}
+
+	for i, opts := range different {
+		if got, _ := Fingerprint("Mary Baker", opts...); got == first {
+			t.Errorf("Fingerprint() of set %d matches a different avatar", i)
+		}
+	}
+
+	if a, b := fingerprintOf(t, " Mary  Baker", Normalize()), fingerprintOf(t, "mary baker", Normalize()); a != b {
+		t.Errorf("Fingerprint() differs between names that normalize the same")
+	}
+
+	if a, b := fingerprintOf(t, " Mary  Baker", Normalize(), Title()), fingerprintOf(t, "mary baker", Normalize(), Title()); a == b {
+		t.Errorf("Fingerprint() ignores the name in the title")
+	}
+
+	// Options the variant ignores draw the same avatar.
+	ignored := [][2][]Option{
+		{{Variant(Marble)}, {Variant(Marble), Expression(Happy), Blink()}},
+		{{Private()}, {Private(), Title()}},
+		{{Label("Mary")}, {Label("Mary"), Title()}},
+		{{Variant(Beam), Expression(Sleeping)}, {Variant(Beam), Expression(Sleeping), Blink()}},
+	}
+
+	for i, sets := range ignored {
+
+		a, b := fingerprintOf(t, "Mary Baker", sets[0]...), fingerprintOf(t, "Mary Baker", sets[1]...)
+		if a != b {
+			t.Errorf("Fingerprint() of ignored set %d differs", i)
+		}
+
+		x, _ := New("Mary Baker", sets[0]...)
+		y, _ := New("Mary Baker", sets[1]...)
+		if x != y {
+			t.Errorf("New() of ignored set %d differs", i)
+		}
+	}
+
+	if a, b := fingerprintOf(t, "Mary Baker", Variant(Beam)), fingerprintOf(t, "Mary Baker", Variant(Beam), Expression(Happy)); a == b {
+		t.Errorf("Fingerprint() ignores the expression of a Beam avatar")
+	}
+
+	if _, err := Fingerprint(""); !errors.Is(err, ErrEmptyName) {
+		t.Errorf("Fingerprint() of an empty name returned %v", err)
+	}
+
+	g := NewGenerator(nil, Variant(Ring))
+	key, _ := g.Fingerprint("Mary Baker")
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/Mary%20Baker.svg", nil)
+	req.Header.Set("If-None-Match", `W/"other", "`+key+`"`)
+
+	g.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotModified || rec.Header().Get("ETag") != `"`+key+`"` {
+		t.Errorf("ServeHTTP() = %d with ETag %q", rec.Code, rec.Header().Get("ETag"))
+	}
+
+}
+
+func TestFingerprintGolden(t *testing.T) {
+
+	// These only change with fingerprintVersion. A new field, or a new way of
+	// writing one, needs a new version and new values here.
+	tests := []struct {
+		opts []Option
+		want string
+	}{
+		{nil, "v2-1dc4c4b8e9756522c68b89e44799f0ff0f396af285fa123b9202bce5df0071c9"},
+		{[]Option{Variant(Beam), Size(80, "px"), Title(), Seed(3)}, "v2-7606e36e7a2e109655e7784ef8fe6f9035b4b80f9905e24444014f567eb26ac3"},
+		{[]Option{Variant(Sunset), Colors("#264653", "#2a9d8f", "#e9c46a", "#f4a261", "#e76f51"), Classes("avatar"), Shape(Hexagon), Badge(TopLeft, "#22C55E", "3"), Border("#FFFFFF", 5), StoryRing(3, 2), Padding(5), Rotate(45), Zoom(1.2), Minify()}, "v2-36110f43661c02d402fea8a125c9495102dcb3b1ae219df6e801f4ee9d9adfc3"},
+	}
+
+	for i, tt := range tests {
+		if got := fingerprintOf(t, "Mary Baker", tt.opts...); got != tt.want {
+			t.Errorf("Fingerprint() of set %d = %s, want %s", i, got, tt.want)
+		}
+	}
+
+	// Every field of config is either fingerprinted or left out on purpose.
+	var (
+		c         = config{}
+		canonical = "\n" + c.canonical()
+		kind      = reflect.TypeOf(c)
+	)
+
+	for i := 0; i < kind.NumField(); i++ {
+
+		name := kind.Field(i).Name
+
+		if !strings.Contains(canonical, "\n"+name+"=") && !slices.Contains(unfingerprinted, name) {
+			t.Errorf("config.%s is missing from the fingerprint", name)
+		}
+	}
+
+}
+
+func fingerprintOf(t *testing.T, name string, opts ...Option) string {
+
+	t.Helper()
+
+	key, err := Fingerprint(name, opts...)
+	if err != nil {
+		t.Errorf("Fingerprint() error = %v", err)
+	}
+
+	return key
+}
+
+func TestConfig(t *testing.T) {
+
+	want, _ := New("Mary Baker", Size(2.5, "rem"), Square(), Title(), Variant(Beam), Colors("#264653", "#2a9d8f", "#e9c46a", "#f4a261", "#e76f51"), Classes("avatar", "round"))
+
+	var fromJSON Config
+
+	if err := json.Unmarshal([]byte(`{"size":"2.5rem","square":true,"title":true,"variant":"Beam","colors":["#264653","#2a9d8f","#e9c46a","#f4a261","#e76f51"],"classes":["avatar","round"]}`), &fromJSON); err != nil {
+		t.Errorf("json.Unmarshal() error = %v", err)
+		return
+	}
+
+	query, err := url.ParseQuery("size=2.5rem&square&title=true&variant=beam&colors=264653,2a9d8f,e9c46a&colors=%23f4a261,e76f51&classes=avatar,round")
+	if err != nil {
+		t.Errorf("url.ParseQuery() error = %v", err)
+		return
+	}
+
+	fromQuery, err := ParseQuery(query)
+	if err != nil {
+		t.Errorf("ParseQuery() error = %v", err)
+		return
+	}
+
+	t.Setenv("AVATAR_SIZE", "2.5rem")
+	t.Setenv("AVATAR_SQUARE", "true")
+	t.Setenv("AVATAR_TITLE", "1")
+	t.Setenv("AVATAR_VARIANT", "beam")
+	t.Setenv("AVATAR_COLORS", "#264653, #2a9d8f, #e9c46a, #f4a261, #e76f51")
+	t.Setenv("AVATAR_CLASSES", "avatar,round")
+
+	fromEnv, err := ParseEnv("AVATAR_")
+	if err != nil {
+		t.Errorf("ParseEnv() error = %v", err)
+		return
+	}
+
+	for source, c := range map[string]Config{"json": fromJSON, "query": fromQuery, "env": fromEnv} {
+
+		opts, err := c.Options()
+		if err != nil {
+			t.Errorf("%s: Options() error = %v", source, err)
+			continue
+		}
+
+		if got, _ := New("Mary Baker", opts...); got != want {
+			t.Errorf("%s: New() = %v, want %v", source, got, want)
+		}
+	}
+
+	tests := []struct {
+		config Config
+		field  string
+		err    error
+	}{
+		{Config{Size: "large"}, "size", ErrInvalidSize},
+		{Config{Variant: "blob"}, "variant", ErrInvalidVariant},
+		{Config{Colors: []string{"#000000"}}, "colors", ErrInvalidColors},
+		{Config{Colors: []string{"#000000", "#111111", "banana", "#333333", "#444444"}}, "colors", ErrInvalidColor},
+		{Config{Classes: []string{"ok", "two words"}}, "classes", ErrInvalidClass},
+		{Config{Size: "-5"}, "size", ErrNegativePixels},
+		{Config{Width: "-5px"}, "width", ErrNegativePixels},
+		{Config{Height: "5banana"}, "height", ErrInvalidUnit},
+	}
+
+	for _, tt := range tests {
+		if _, err := tt.config.Options(); !errors.Is(err, tt.err) || !strings.HasPrefix(err.Error(), tt.field+": ") {
+			t.Errorf("Options() of %+v returned %v", tt.config, err)
+		}
+	}
+
+	if _, err := ParseQuery(url.Values{"square": {"maybe"}}); err == nil || !strings.HasPrefix(err.Error(), "square: ") {
+		t.Errorf("ParseQuery() returned %v", err)
+	}
+
+	t.Setenv("AVATAR_TITLE", "maybe")
+
+	if _, err := ParseEnv("AVATAR_"); err == nil || !strings.HasPrefix(err.Error(), "AVATAR_TITLE: ") {
+		t.Errorf("ParseEnv() returned %v", err)
+	}
+
+	for _, query := range []string{"colors=abc,def,123,456,789&variant=beam", "size=4banana", "classes=%22%3E"} {
+
+		rec := httptest.NewRecorder()
+		NewGenerator(nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/Mary%20Baker.svg?"+query, nil))
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("ServeHTTP() with %q = %d, want %d", query, rec.Code, http.StatusBadRequest)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	NewGenerator(nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/Mary%20Baker.svg?variant=beam&size=64px", nil))
+
+	if got, _ := New("Mary Baker", Variant(Beam), Size(64, "px")); rec.Body.String() != got {
+		t.Errorf("ServeHTTP() ignored the query: %q", rec.Body.String())
+	}
+
+}
+
+func TestOptionError(t *testing.T) {
+
+	tests := []struct {
+		opt    Option
+		option string
+		err    error
+	}{
+		{Size(40, "banana"), "Size", ErrInvalidUnit},
+		{Size(-1, "px"), "Size", ErrNegativePixels},
+		{Size(math.NaN(), "px"), "Size", ErrInvalidSize},
+		{Width(math.Inf(1), "px"), "Width", ErrInvalidSize},
+		{Height(math.Inf(-1), "px"), "Height", ErrInvalidSize},
+		{Variant(Name{"cubist"}), "Variant", ErrInvalidVariant},
+		{Colors("#000000", "#111111", "red", "rgb(0, 0, 0)", `"><script>`), "Colors", ErrInvalidColor},
+		{Colors("#000000", "#111111", "#222222", "#333333", "banana"), "Colors", ErrInvalidColor},
+		{Colors("#abc", "#abcd", "#AABBCCDD", "#abcde", "#111111"), "Colors", ErrInvalidColor},
+		{Colors("rgb(1, 2)", "#111111", "#222222", "#333333", "#444444"), "Colors", ErrInvalidColor},
+		{Colors("hsl(1, 2, 3)", "#111111", "#222222", "#333333", "#444444"), "Colors", ErrInvalidColor},
+		{Colors("", "", "", "", ""), "Colors", ErrEmptyPalette},
+		{Classes("ok", "two words"), "Classes", ErrInvalidClass},
+		{Classes(""), "Classes", ErrInvalidClass},
+		{Badge(TopLeft, "url(#x)", ""), "Badge", ErrInvalidColor},
+		{Border("", 5), "Border", ErrInvalidColor},
+		{Background("#12345"), "Background", ErrInvalidColor},
+		{Zoom(0), "Zoom", ErrInvalidTransform},
+		{Lang("en_GB"), "Lang", ErrInvalidLang},
+	}
+
+	for _, tt := range tests {
+
+		_, err := New("Mary Baker", Title(), tt.opt)
+
+		var optErr *OptionError
+
+		if !errors.As(err, &optErr) || optErr.Option != tt.option || !errors.Is(err, tt.err) {
+			t.Errorf("New() with %s returned %v", tt.option, err)
+		}
+	}
+
+	// Every accepted color works in Beam, which reads it for contrast, and in
+	// images, which paint it.
+	accepted := map[string][3]uint32{
+		"#ff005b":             {0xff, 0x00, 0x5b},
+		"#F05":                {0xff, 0x00, 0x55},
+		"red":                 {0xff, 0x00, 0x00},
+		"Teal":                {0x00, 0x80, 0x80},
+		"rgb(1, 2, 3)":        {0x01, 0x02, 0x03},
+		"rgba(100% 0% 50%/1)": {0xff, 0x00, 0x80},
+		"hsl(120, 100%, 25%)": {0x00, 0x80, 0x00},
+	}
+
+	for color, want := range accepted {
+
+		colors := Colors(color, color, color, color, color)
+
+		if _, err := New("Mary Baker", Variant(Beam), colors); err != nil {
+			t.Errorf("Beam with %q returned %v", color, err)
+		}
+
+		img, err := NewImage("Mary Baker", 20, Square(), colors)
+		if err != nil {
+			t.Errorf("NewImage() with %q returned %v", color, err)
+			continue
+		}
+
+		r, g, b, a := img.At(10, 10).RGBA()
+
+		for i, got := range []uint32{r >> 8, g >> 8, b >> 8} {
+			if got+1 < want[i] || got > want[i]+1 || a>>8 != 0xff {
+				t.Errorf("NewImage() with %q painted %v", color, img.At(10, 10))
+				break
+			}
+		}
+	}
+
+	for _, color := range []string{"banana", "#abcde", "rgb(1,2)", "url(#x)"} {
+
+		colors := Colors(color, color, color, color, color)
+
+		if _, err := New("Mary Baker", Variant(Beam), colors); !errors.Is(err, ErrInvalidColor) {
+			t.Errorf("Beam with %q returned %v", color, err)
+		}
+
+		if _, err := NewImage("Mary Baker", 20, colors); !errors.Is(err, ErrInvalidColor) {
+			t.Errorf("NewImage() with %q returned %v", color, err)
+		}
+	}
+
+	_, err := New("Mary Baker", Size(40, "banana"), Colors("#000000", "#111111", "#222222", "#333333", "nope!"))
+
+	if !errors.Is(err, ErrInvalidUnit) || !errors.Is(err, ErrInvalidColor) {
+		t.Errorf("New() did not join the errors: %v", err)
+	}
+
+	if want := `Colors("nope!"): invalid color`; !strings.Contains(err.Error(), want) {
+		t.Errorf("error %q does not contain %q", err, want)
+	}
+
+	for _, opt := range []Option{Size(40, "VW"), Size(50, "%"), Size(40, ""), Colors("#0A0310", "#49007e", "#FF005B", "#ff7d10", "#FFB238")} {
+		if _, err := New("Mary Baker", opt); err != nil {
+			t.Errorf("New() returned %v", err)
+		}
+	}
+
+}
+
+func TestResponsive(t *testing.T) {
+
+	tests := []struct {
+		opts []Option
+		want string
+	}{
+		{[]Option{Responsive()}, ` xmlns="http://www.w3.org/2000/svg">`},
+		{[]Option{Size(3, "em"), Width(100, "%")}, ` xmlns="http://www.w3.org/2000/svg" width="100%" height="3em">`},
+		{[]Option{Responsive(), Height(10, "VW")}, ` xmlns="http://www.w3.org/2000/svg" height="10vw">`},
+	}
+
+	for _, tt := range tests {
+
+		svg, err := New("Mary Baker", tt.opts...)
+		if err != nil {
+			t.Errorf("New() error = %v", err)
+			continue
+		}
+
+		if head, _, _ := strings.Cut(svg, "><"); !strings.HasSuffix(head+">", tt.want) {
+			t.Errorf("New() = %s>, want %s", head, tt.want)
+		}
+	}
+
+	img, err := NewImage("Mary Baker", 40, Responsive())
+	if err != nil || img.Bounds().Dx() != 40 {
+		t.Errorf("NewImage() of a responsive avatar returned %v", err)
+	}
+
+	for _, opt := range []Option{Width(40, "banana"), Height(-1, "px"), Width(math.Inf(1), "px"), Height(math.NaN(), "em")} {
+		if _, err := New("Mary Baker", opt); !errors.Is(err, ErrInvalidUnit) && !errors.Is(err, ErrNegativePixels) && !errors.Is(err, ErrInvalidSize) {
+			t.Errorf("New() returned %v", err)
+		}
+	}
+
+	opts, err := Config{Responsive: true, Width: "5rem"}.Options()
+	if err != nil {
+		t.Errorf("Options() error = %v", err)
+		return
+	}
+
+	got, _ := New("Mary Baker", opts...)
+	want, _ := New("Mary Baker", Responsive(), Width(5, "rem"))
+
+	if got != want {
+		t.Errorf("Config with Responsive = %s, want %s", got, want)
+	}
+
+	if _, err := (Config{Height: "5parsecs"}).Options(); !errors.Is(err, ErrInvalidUnit) || !strings.HasPrefix(err.Error(), "height: ") {
+		t.Errorf("Options() returned %v", err)
+	}
+
+}
+
+func TestVariantFromHash(t *testing.T) {
+
+	var (
+		seen   = map[Name]int{}
+		colors = map[int]map[Name]bool{}
+	)
+
+	for i := 0; i < 200; i++ {
+
+		name := fmt.Sprintf("user%d", i)
+
+		c, err := build(name, VariantFromHash())
+		if err != nil {
+			t.Errorf("build() error = %v", err)
+			return
+		}
+
+		want, _ := New(name, Variant(c.variant))
+
+		if got, _ := New(name, VariantFromHash()); got != want {
+			t.Errorf("New(%q) is not the %q variant", name, c.variant)
+		}
+
+		seen[c.variant]++
+
+		first := hashCode(name) % len(defaultColors)
+
+		if colors[first] == nil {
+			colors[first] = map[Name]bool{}
+		}
+
+		colors[first][c.variant] = true
+	}
+
+	if len(seen) != 6 {
+		t.Errorf("VariantFromHash() picked %d of the 6 variants: %v", len(seen), seen)
+	}
+
+	for color, variants := range colors {
+		if len(variants) < 2 {
+			t.Errorf("names with color %d always get the same variant", color)
+		}
+	}
+
+	for i := 0; i < 50; i++ {
+
+		c, _ := build(fmt.Sprintf("user%d", i), VariantFromHash(Beam, Ring))
+
+		if c.variant != Beam && c.variant != Ring {
+			t.Errorf("VariantFromHash(Beam, Ring) picked %q", c.variant)
+		}
+	}
+
+	if c, _ := build("Mary Baker", VariantFromHash(Ring), Variant(Pixel)); c.variant != Pixel {
+		t.Errorf("Variant() after VariantFromHash() = %q", c.variant)
+	}
+
+	if _, err := New("Mary Baker", VariantFromHash(Name{"cubist"})); !errors.Is(err, ErrInvalidVariant) {
+		t.Errorf("VariantFromHash() of an invalid variant returned %v", err)
+	}
+
+}
+
+func TestSeed(t *testing.T) {
+
+	plain, _ := New("Mary Baker", Variant(Beam))
+
+	if seeded, _ := New("Mary Baker", Variant(Beam), Seed(0)); seeded != plain {
+		t.Errorf("Seed(0) changed the avatar")
+	}
+
+	first, _ := New("Mary Baker", Variant(Beam), Seed(1))
+	again, _ := New("Mary Baker", Variant(Beam), Seed(1))
+
+	if first != again {
+		t.Errorf("Seed(1) is not deterministic")
+	}
+
+	// Small variants such as Ring can match by chance, but spelling the seed
+	// out in the name must not give the same hash.
+	seeded, _ := build("Mary", Seed(1))
+
+	for _, name := range []string{"Mary#1", "1:Mary", "Mary1", "Mary 1"} {
+
+		if c, _ := build(name); c.hash() == seeded.hash() {
+			t.Errorf("%q has the hash of Seed(1)", name)
+		}
+
+		if spelled, _ := New(name, Variant(Beam)); spelled == Render("Mary", Variant(Beam), Seed(1)).String() {
+			t.Errorf("New(%q) draws the avatar of Seed(1)", name)
+		}
+	}
+
+	list, err := Candidates("Mary Baker", 8, Variant(Beam))
+	if err != nil || len(list) != 8 {
+		t.Errorf("Candidates() = %d avatars, %v", len(list), err)
+		return
+	}
+
+	var (
+		faces = map[string]bool{}
+		ids   = map[string]bool{}
+	)
+
+	for seed, svg := range list {
+
+		want, _ := New("Mary Baker", Variant(Beam), Seed(seed))
+
+		internals, _ := getInternals(want)
+		faces[internals] = true
+
+		if strings.ReplaceAll(svg, fmt.Sprintf("s%d-", seed), "") != want {
+			t.Errorf("candidate %d is not the avatar of its seed", seed)
+		}
+
+		for _, id := range regexp.MustCompile(`id="([^"]+)"`).FindAllStringSubmatch(svg, -1) {
+
+			if ids[id[1]] {
+				t.Errorf("candidate %d repeats the id %q", seed, id[1])
+			}
+
+			ids[id[1]] = true
+		}
+	}
+
+	if len(faces) < 7 {
+		t.Errorf("Candidates() drew %d different avatars out of 8", len(faces))
+	}
+
+	variants := map[Name]bool{}
+
+	for seed := 0; seed < 20; seed++ {
+		c, _ := build("Mary Baker", VariantFromHash(), Seed(seed))
+		variants[c.variant] = true
+	}
+
+	if len(variants) < 2 {
+		t.Errorf("Seed() does not change the variant picked by VariantFromHash()")
+	}
+
+	c, err := ParseQuery(url.Values{"seed": {"3"}})
+	if err != nil || c.Seed != 3 {
+		t.Errorf("ParseQuery() = %+v, %v", c, err)
+	}
+
+	if _, err := ParseQuery(url.Values{"seed": {"three"}}); err == nil || !strings.HasPrefix(err.Error(), "seed: ") {
+		t.Errorf("ParseQuery() returned %v", err)
+	}
+
+	if list, err := Candidates("", 2); err == nil || list != nil {
+		t.Errorf("Candidates() of an empty name returned %v", err)
+	}
+
+}
+
+func TestGallery(t *testing.T) {
+
+	var (
+		names    = []string{"Mary Baker", "Grace <Hopper>"}
+		palettes = []Palette{{"Sunrise", []string{"#264653", "#2a9d8f", "#e9c46a", "#f4a261", "#e76f51"}}, {"Night", defaultColors}}
+	)
+
+	page, err := Gallery(names, nil, palettes, Title())
+	if err != nil {
+		t.Errorf("Gallery() error = %v", err)
+		return
+	}
+
+	if got := strings.Count(page, "<svg "); got != 2*6*2 {
+		t.Errorf("Gallery() has %d avatars, want %d", got, 2*6*2)
+	}
+
+	for _, label := range []string{"Sunrise", "Night", "marble", "pixel", "Grace &lt;Hopper&gt;"} {
+		if !strings.Contains(page, label) {
+			t.Errorf("Gallery() is missing the label %q", label)
+		}
+	}
+
+	if strings.Contains(page, "<Hopper>") {
+		t.Errorf("Gallery() did not escape a name")
+	}
+
+	want, _ := New("Mary Baker", Title(), Variant(Beam), Colors("#264653", "#2a9d8f", "#e9c46a", "#f4a261", "#e76f51"))
+	if !strings.Contains(strings.ReplaceAll(page, "p0n0v1-", ""), want) {
+		t.Errorf("Gallery() does not hold the avatar of its cell")
+	}
+
+	for _, output := range []func() (string, error){
+		func() (string, error) { return Gallery(names, nil, palettes) },
+		func() (string, error) { return GallerySVG(names, nil, palettes) },
+		func() (string, error) { return GallerySVG(names, nil, palettes, Minify()) },
+	} {
+
+		out, err := output()
+		if err != nil {
+			t.Errorf("gallery error = %v", err)
+			continue
+		}
+
+		ids := map[string]bool{}
+
+		for _, id := range regexp.MustCompile(`id="([^"]+)"`).FindAllStringSubmatch(out, -1) {
+
+			if ids[id[1]] {
+				t.Errorf("gallery repeats the id %q", id[1])
+			}
+
+			ids[id[1]] = true
+		}
+	}
+
+	svg, err := GallerySVG(names, []Name{Ring, Beam}, nil)
+	if err != nil {
+		t.Errorf("GallerySVG() error = %v", err)
+		return
+	}
+
+	if err := xml.Unmarshal([]byte(svg), new(struct{})); err != nil {
+		t.Errorf("GallerySVG() is not well formed: %v", err)
+	}
+
+	if got := strings.Count(svg, "<svg "); got != 1+2*2 {
+		t.Errorf("GallerySVG() has %d svgs, want %d", got, 1+2*2)
+	}
+
+	if _, err := rasterize(svg, 100); err != nil {
+		t.Errorf("rasterize() of the gallery error = %v", err)
+	}
+
+	if _, err := Gallery(nil, nil, nil); !errors.Is(err, ErrEmptyName) {
+		t.Errorf("Gallery() without names returned %v", err)
+	}
+
+	if _, err := Gallery(names, []Name{{"cubist"}}, nil); !errors.Is(err, ErrInvalidVariant) {
+		t.Errorf("Gallery() with an invalid variant returned %v", err)
+	}
+
+	if _, err := GallerySVG(names, nil, []Palette{{"Short", []string{"#000000"}}}); !errors.Is(err, ErrInvalidColors) {
+		t.Errorf("GallerySVG() with a short palette returned %v", err)
+	}
+
+}
+
+func TestSunsetIDs(t *testing.T) {
+
+	ids := map[string]string{}
+
+	for _, name := range []string{"x@y.com", "xy.com", "a.b", "ab", "a-b"} {
+
+		svg, err := New(name, Variant(Sunset))
+		if err != nil {
+			t.Errorf("New() error = %v", err)
+			continue
+		}
+
+		id := regexp.MustCompile(`id="(gradient_paint0_linear_[^"]*)"`).FindStringSubmatch(svg)
+		if id == nil {
+			t.Errorf("New(%q) has no gradient", name)
+			continue
+		}
+
+		if other, ok := ids[id[1]]; ok {
+			t.Errorf("%q and %q share the id %q", name, other, id[1])
+		}
+
+		ids[id[1]] = name
+	}
+
+}
+
+// TestGeneratorRace shares the options of a Generator between goroutines, for
+// go test -race to check.
+func TestGeneratorRace(t *testing.T) {
+
+	var (
+		g    = NewGenerator(NewMemoryCache(0, 0), Normalize(), VariantFromHash())
+		want = Render("mary baker", Normalize(), VariantFromHash()).String()
+		wg   sync.WaitGroup
+	)
+
+	for i := 0; i < 8; i++ {
+
+		wg.Add(1)
+
+		go func() {
+
+			defer wg.Done()
+
+			if got, err := g.New("Mary  Baker"); err != nil || got != want {
+				t.Errorf("Generator.New() = %v, %v", got, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+}
@@ -13,8 +13,8 @@ type marbleProperties struct {
 	rotate     float64
 }
 
-func generateMarbleColors(name string, colors []string) map[int]marbleProperties {
-	numFromName := hashCode(name)
+func generateMarbleColors(hash int, colors []string) map[int]marbleProperties {
+	numFromName := hash
 
 	elementsProperties := map[int]marbleProperties{}
 
@@ -36,8 +36,9 @@ func (a config) marble() string {
 
 	dsize := 80
 
-	properties := generateMarbleColors(a.name, a.colors)
+	properties := generateMarbleColors(a.hash(), a.colors)
 	maskID := "mask__marble"
+	filterID := a.id("prefix__filter0_f")
 
 	var svg strings.Builder
 
@@ -45,11 +46,27 @@ func (a config) marble() string {
 
 	svg.WriteString(fmt.Sprintf(`<rect width="%d" height="%d" fill="%s"></rect>`, dsize, dsize, properties[0].color))
 
-	svg.WriteString(fmt.Sprintf(`<path filter="url(#prefix__filter0_f)" d="M32.414 59.35L50.376 70.5H72.5v-71H33.728L26.5 13.381l19.057 27.08L32.414 59.35z" fill="%s" transform="translate(%.0f %.0f) rotate(%.0f %d %d) scale(%.1f)"></path>`, properties[1].color, properties[1].translateX, properties[1].translateY, properties[1].rotate, dsize/2, dsize/2, properties[2].scale))
+	var motions []motion
 
-	svg.WriteString(fmt.Sprintf(`<path filter="url(#prefix__filter0_f)" style="mix-blend-mode: overlay;" d="M22.216 24L0 46.75l14.108 38.129L78 86l-3.081-59.276-22.378 4.005 12.972 20.186-23.35 27.395L22.215 24z" fill="%s" transform="translate(%.0f %.0f) rotate(%.0f %d %d) scale(%.1f)"></path>`, properties[2].color, properties[2].translateX, properties[2].translateY, properties[2].rotate, dsize/2, dsize/2, properties[2].scale))
+	if a.animate {
+		motions = marbleMotions(a.hash())
+		a.animated(&svg, motions[0])
+	}
+
+	svg.WriteString(fmt.Sprintf(`<path filter="url(#%s)" d="M32.414 59.35L50.376 70.5H72.5v-71H33.728L26.5 13.381l19.057 27.08L32.414 59.35z" fill="%s" transform="translate(%.0f %.0f) rotate(%.0f %d %d) scale(%.1f)"></path>`, filterID, properties[1].color, properties[1].translateX, properties[1].translateY, properties[1].rotate, dsize/2, dsize/2, properties[2].scale))
+
+	if a.animate {
+		svg.WriteString(`</g>`)
+		a.animated(&svg, motions[1])
+	}
+
+	svg.WriteString(fmt.Sprintf(`<path filter="url(#%s)" style="mix-blend-mode: overlay;" d="M22.216 24L0 46.75l14.108 38.129L78 86l-3.081-59.276-22.378 4.005 12.972 20.186-23.35 27.395L22.215 24z" fill="%s" transform="translate(%.0f %.0f) rotate(%.0f %d %d) scale(%.1f)"></path>`, filterID, properties[2].color, properties[2].translateX, properties[2].translateY, properties[2].rotate, dsize/2, dsize/2, properties[2].scale))
+
+	if a.animate {
+		svg.WriteString(`</g>`)
+	}
 
-	a.end(&svg, `<filter id="prefix__filter0_f" filterUnits="userSpaceOnUser" colorInterpolationFilters="sRGB"><feFlood flood-opacity="0" result="BackgroundImageFix" /><feBlend in="SourceGraphic" in2="BackgroundImageFix" result="shape" /><feGaussianBlur stdDeviation="7" result="effect1_foregoundBlur"/></filter>`)
+	a.end(&svg, dsize, a.style(motions...), fmt.Sprintf(`<filter id="%s" filterUnits="userSpaceOnUser" colorInterpolationFilters="sRGB"><feFlood flood-opacity="0" result="BackgroundImageFix" /><feBlend in="SourceGraphic" in2="BackgroundImageFix" result="shape" /><feGaussianBlur stdDeviation="7" result="effect1_foregoundBlur"/></filter>`, filterID))
 
 	return svg.String()
 }
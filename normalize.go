@@ -0,0 +1,92 @@
+package goboringavatars
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Normalizer rewrites a name before it is hashed, so different spellings of
+// the same name get the same avatar.
+//
+// The package has no NFC or NFKC normalizer, as it only depends on the
+// standard library, which has no Unicode normalization tables. The String
+// method of the forms in golang.org/x/text/unicode/norm is a Normalizer, so
+// callers that need it pass it first:
+//
+//	goboringavatars.Normalize(norm.NFKC.String, goboringavatars.CollapseSpace, goboringavatars.FoldCase)
+type Normalizer func(string) string
+
+// Normalize rewrites the name with the normalizers, in order, before it is
+// hashed. The title still shows the name as it was given. Without normalizers,
+// the name is trimmed, its whitespace collapsed and its case folded.
+func Normalize(normalizers ...Normalizer) Option {
+
+	// The option is shared, such as by a Generator, so it only reads the list.
+	if len(normalizers) == 0 {
+		normalizers = []Normalizer{CollapseSpace, FoldCase}
+	}
+
+	return option(func(c *config) error {
+
+		c.normalizers = append(c.normalizers, normalizers...)
+
+		return nil
+	})
+}
+
+// Trim removes the whitespace around the name.
+func Trim(name string) string {
+	return strings.TrimSpace(name)
+}
+
+// CollapseSpace trims the name and replaces each run of whitespace inside it with a single space.
+func CollapseSpace(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// FoldCase maps every letter of the name to the same case, including letters
+// such as the Kelvin sign that only fold to another one.
+func FoldCase(name string) string {
+	return strings.ToLower(strings.Map(func(r rune) rune {
+
+		// The smallest rune of the folding orbit stands for all of them.
+		least := r
+
+		for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+			least = min(least, f)
+		}
+
+		return least
+	}, name))
+}
+
+// CanonicalEmail lowercases an email address and removes the +tag from its
+// local part, so "Mary+news@Example.com" becomes "mary@example.com". Names
+// that aren't email addresses are only trimmed.
+func CanonicalEmail(name string) string {
+
+	name = strings.TrimSpace(name)
+
+	at := strings.LastIndex(name, "@")
+	if at <= 0 || at == len(name)-1 {
+		return name
+	}
+
+	local, domain := strings.ToLower(name[:at]), strings.ToLower(name[at+1:])
+
+	if plus := strings.Index(local, "+"); plus > 0 {
+		local = local[:plus]
+	}
+
+	return local + "@" + domain
+}
+
+// normalize returns the name after the normalizers.
+func (a config) normalize(name string) string {
+
+	for _, n := range a.normalizers {
+		name = n(name)
+	}
+
+	return name
+}
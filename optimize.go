@@ -0,0 +1,632 @@
+package goboringavatars
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// minifyPrecision is the number of decimals Minify keeps without Precision.
+const minifyPrecision = 3
+
+var (
+	numberPattern    = regexp.MustCompile(`[-+]?(?:\d+\.?\d*|\.\d+)(?:[eE][-+]?\d+)?`)
+	referencePattern = regexp.MustCompile(`url\(#([^)]+)\)`)
+	transformPattern = regexp.MustCompile(`(\w+)\(([^)]*)\)`)
+)
+
+// numericAttributes hold numbers, or lists of them, that Precision rounds.
+var numericAttributes = map[string]bool{
+	"x": true, "y": true, "width": true, "height": true, "rx": true, "ry": true,
+	"cx": true, "cy": true, "r": true, "x1": true, "y1": true, "x2": true, "y2": true,
+	"d": true, "points": true, "transform": true, "stroke-width": true, "font-size": true,
+	"stdDeviation": true, "dx": true, "dy": true, "offset": true, "opacity": true,
+	"fill-opacity": true, "stroke-opacity": true, "flood-opacity": true,
+}
+
+// defaultAttributes are values that draw the same as leaving the attribute out.
+var defaultAttributes = map[string]map[string]string{
+	"rect":           {"x": "0", "y": "0"},
+	"circle":         {"cx": "0", "cy": "0"},
+	"ellipse":        {"cx": "0", "cy": "0"},
+	"line":           {"x1": "0", "y1": "0", "x2": "0", "y2": "0"},
+	"stop":           {"offset": "0"},
+	"svg":            {"preserveAspectRatio": "xMidYMid meet"},
+	"linearGradient": {"gradientUnits": "objectBoundingBox"},
+	"":               {"opacity": "1", "fill-opacity": "1", "stroke-opacity": "1", "style": "", "transform": ""},
+}
+
+// Minify shortens the SVG output without changing how it is drawn. Numbers
+// are rounded, to 3 decimals unless Precision is set, identity transforms and
+// default attributes are dropped, neighbouring squares of the same color are
+// merged into one path, definitions are gathered into a single defs element
+// and ids are shortened to hashes of what they name.
+func Minify() Option {
+	return option(func(c *config) error {
+		c.minify = true
+		return nil
+	})
+}
+
+// Precision rounds every number in the SVG output to the decimals, from 0 to 10.
+func Precision(decimals int) Option {
+	return option(func(c *config) error {
+
+		if decimals < 0 || decimals > 10 {
+			return &OptionError{"Precision", decimals, ErrInvalidPrecision}
+		}
+
+		c.precision = decimals
+
+		return nil
+	})
+}
+
+// optimize applies Minify and Precision to a finished svg. Nested avatars are
+// left for the svg holding them.
+func (c config) optimize(svg string) (string, error) {
+
+	if c.viewport != nil || (!c.minify && c.precision < 0) {
+		return svg, nil
+	}
+
+	root, err := parseMarkup(svg)
+	if err != nil {
+		return "", err
+	}
+
+	o := optimizer{precision: c.precision, minify: c.minify}
+
+	if o.precision < 0 {
+		o.precision = minifyPrecision
+	}
+
+	o.element(root, true)
+
+	if o.minify {
+		o.gather(root)
+		o.shorten(root)
+	}
+
+	s := strings.Builder{}
+	root.write(&s, o.minify)
+
+	return s.String(), nil
+}
+
+// markup is an element of the svg, or a run of text when it has no name.
+type markup struct {
+	name     string
+	attrs    []xml.Attr
+	children []*markup
+	text     string
+}
+
+// parseMarkup reads the svg into a tree, keeping the order of the attributes.
+func parseMarkup(svg string) (*markup, error) {
+
+	var (
+		d     = xml.NewDecoder(strings.NewReader(svg))
+		stack = []*markup{{}}
+	)
+
+	for {
+
+		token, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		parent := stack[len(stack)-1]
+
+		switch t := token.(type) {
+		case xml.StartElement:
+
+			m := &markup{name: qualified(t.Name), attrs: t.Attr}
+
+			parent.children = append(parent.children, m)
+			stack = append(stack, m)
+
+		case xml.EndElement:
+
+			if len(stack) < 2 {
+				return nil, errors.New("unbalanced svg markup")
+			}
+
+			stack = stack[:len(stack)-1]
+
+		case xml.CharData:
+			parent.children = append(parent.children, &markup{text: string(t)})
+		}
+	}
+
+	if len(stack) != 1 || len(stack[0].children) != 1 || stack[0].children[0].name != "svg" {
+		return nil, errors.New("svg markup must have a single svg element")
+	}
+
+	return stack[0].children[0], nil
+}
+
+// qualified returns the name with its prefix, as it was written.
+func qualified(name xml.Name) string {
+
+	if name.Space == "" {
+		return name.Local
+	}
+
+	return name.Space + ":" + name.Local
+}
+
+// attr returns the value of an attribute, and whether it is set.
+func (m *markup) attr(name string) (string, bool) {
+
+	for _, a := range m.attrs {
+		if qualified(a.Name) == name {
+			return a.Value, true
+		}
+	}
+
+	return "", false
+}
+
+// write serializes the markup, closing empty elements with /> when short.
+func (m *markup) write(s *strings.Builder, short bool) {
+
+	if m.name == "" {
+		s.WriteString(escapeMarkup(m.text, false))
+		return
+	}
+
+	s.WriteString("<" + m.name)
+
+	for _, a := range m.attrs {
+		s.WriteString(fmt.Sprintf(` %s="%s"`, qualified(a.Name), escapeMarkup(a.Value, true)))
+	}
+
+	if short && len(m.children) == 0 {
+		s.WriteString("/>")
+		return
+	}
+
+	s.WriteString(">")
+
+	for _, child := range m.children {
+		child.write(s, short)
+	}
+
+	s.WriteString("</" + m.name + ">")
+}
+
+// escapeMarkup escapes the characters that would end text or an attribute.
+func escapeMarkup(s string, attribute bool) string {
+
+	r := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+	if attribute {
+		r = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+	}
+
+	return r.Replace(s)
+}
+
+// optimizer rewrites the markup of an svg.
+type optimizer struct {
+	precision int
+	minify    bool
+}
+
+// element rewrites an element and the ones inside it. The root keeps its
+// width and height, which are the size set by the caller.
+func (o optimizer) element(m *markup, root bool) {
+
+	attrs := m.attrs[:0]
+
+	for _, a := range m.attrs {
+
+		name := qualified(a.Name)
+
+		if numericAttributes[name] && !(root && (name == "width" || name == "height")) {
+			a.Value = o.numbers(a.Value, name == "d")
+		}
+
+		if o.minify && name == "transform" {
+			a.Value = simplifyTransform(a.Value)
+		}
+
+		if o.minify && o.redundant(m.name, name, a.Value) {
+			continue
+		}
+
+		attrs = append(attrs, a)
+	}
+
+	m.attrs = attrs
+
+	for _, child := range m.children {
+		if child.name != "" {
+			o.element(child, false)
+		}
+	}
+
+	if !o.minify {
+		return
+	}
+
+	// Groups without attributes don't change their children.
+	children := make([]*markup, 0, len(m.children))
+
+	for _, child := range m.children {
+
+		if child.name == "g" && len(child.attrs) == 0 {
+			children = append(children, child.children...)
+			continue
+		}
+
+		children = append(children, child)
+	}
+
+	m.children = o.merge(children)
+}
+
+// numbers rounds the numbers in an attribute value to the precision.
+func (o optimizer) numbers(value string, path bool) string {
+	return numberPattern.ReplaceAllStringFunc(value, func(n string) string {
+
+		// Arc flags can be packed together, such as the "00" of "a38 38 0 00-76 0".
+		if path && len(n) > 1 && n[0] == '0' && n[1] >= '0' && n[1] <= '9' {
+			return n
+		}
+
+		f, err := strconv.ParseFloat(n, 64)
+		if err != nil {
+			return n
+		}
+
+		return o.format(f)
+	})
+}
+
+// format writes the number at the precision, without a leading zero when minifying.
+func (o optimizer) format(f float64) string {
+
+	scale := math.Pow(10, float64(o.precision))
+	f = math.Round(f*scale) / scale
+
+	// Avoid writing negative zero as "-0".
+	if f == 0 {
+		f = 0
+	}
+
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+
+	if o.minify {
+		switch {
+		case strings.HasPrefix(s, "0."):
+			s = s[1:]
+		case strings.HasPrefix(s, "-0."):
+			s = "-" + s[2:]
+		}
+	}
+
+	return s
+}
+
+// redundant reports whether an attribute draws the same as leaving it out.
+func (o optimizer) redundant(element, name, value string) bool {
+
+	if v, ok := defaultAttributes[element][name]; ok && v == value {
+		return true
+	}
+
+	v, ok := defaultAttributes[""][name]
+
+	return ok && v == value
+}
+
+// simplifyTransform drops the parts of a transform that leave the element in place.
+func simplifyTransform(value string) string {
+
+	var parts []string
+
+	for _, part := range transformPattern.FindAllStringSubmatch(value, -1) {
+
+		var (
+			args     = parseNumbers(part[2])
+			identity bool
+		)
+
+		switch part[1] {
+		case "translate", "skewX", "skewY":
+			identity = !slices.ContainsFunc(args, func(f float64) bool { return f != 0 })
+		case "rotate":
+			identity = len(args) > 0 && args[0] == 0
+		case "scale":
+			identity = !slices.ContainsFunc(args, func(f float64) bool { return f != 1 })
+		case "matrix":
+			identity = slices.Equal(args, []float64{1, 0, 0, 1, 0, 0})
+		}
+
+		if !identity {
+			parts = append(parts, part[0])
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// square is a rect that can be merged into a path with others of its color.
+type square struct {
+	x, y, width, height float64
+	fill                string
+}
+
+// plainRect returns the rect as a square, if it has nothing but a position, a size and a fill.
+func plainRect(m *markup) (square, bool) {
+
+	if m.name != "rect" || len(m.children) > 0 {
+		return square{}, false
+	}
+
+	var (
+		s      square
+		values = map[string]*float64{"x": &s.x, "y": &s.y, "width": &s.width, "height": &s.height}
+	)
+
+	for _, a := range m.attrs {
+
+		name := qualified(a.Name)
+
+		if name == "fill" {
+			s.fill = a.Value
+			continue
+		}
+
+		target, ok := values[name]
+		if !ok {
+			return square{}, false
+		}
+
+		f, err := strconv.ParseFloat(a.Value, 64)
+		if err != nil {
+			return square{}, false
+		}
+
+		*target = f
+	}
+
+	return s, s.fill != "" && s.width > 0 && s.height > 0
+}
+
+// merge joins runs of plain rects that don't overlap into one path for each
+// color. As they don't overlap, the order they are drawn in doesn't matter.
+func (o optimizer) merge(children []*markup) []*markup {
+
+	var (
+		out []*markup
+		run []square
+	)
+
+	flush := func() {
+
+		overlap := false
+
+		for i, a := range run {
+			for _, b := range run[i+1:] {
+				if a.x < b.x+b.width && b.x < a.x+a.width && a.y < b.y+b.height && b.y < a.y+a.height {
+					overlap = true
+				}
+			}
+		}
+
+		var (
+			colors = map[string][]square{}
+			order  []string
+		)
+
+		for _, s := range run {
+
+			if overlap {
+				out = append(out, s.markup(o))
+				continue
+			}
+
+			if _, ok := colors[s.fill]; !ok {
+				order = append(order, s.fill)
+			}
+
+			colors[s.fill] = append(colors[s.fill], s)
+		}
+
+		for _, fill := range order {
+
+			// A color used by one square alone is shorter as a rect.
+			if len(colors[fill]) == 1 {
+				out = append(out, colors[fill][0].markup(o))
+				continue
+			}
+
+			d := strings.Builder{}
+
+			for _, s := range colors[fill] {
+				d.WriteString(fmt.Sprintf("M%s %sh%sv%sh-%sz", o.format(s.x), o.format(s.y), o.format(s.width), o.format(s.height), o.format(s.width)))
+			}
+
+			out = append(out, &markup{name: "path", attrs: []xml.Attr{
+				{Name: xml.Name{Local: "d"}, Value: d.String()},
+				{Name: xml.Name{Local: "fill"}, Value: fill},
+			}})
+		}
+
+		run = run[:0]
+	}
+
+	for _, child := range children {
+
+		if s, ok := plainRect(child); ok {
+			run = append(run, s)
+			continue
+		}
+
+		flush()
+		out = append(out, child)
+	}
+
+	flush()
+
+	return out
+}
+
+// markup returns the square as a rect.
+func (s square) markup(o optimizer) *markup {
+
+	m := &markup{name: "rect"}
+
+	for _, a := range []struct {
+		name  string
+		value float64
+	}{{"x", s.x}, {"y", s.y}, {"width", s.width}, {"height", s.height}} {
+		if a.value != 0 || a.name == "width" || a.name == "height" {
+			m.attrs = append(m.attrs, xml.Attr{Name: xml.Name{Local: a.name}, Value: o.format(a.value)})
+		}
+	}
+
+	m.attrs = append(m.attrs, xml.Attr{Name: xml.Name{Local: "fill"}, Value: s.fill})
+
+	return m
+}
+
+// gather moves every definition and mask into a single defs element at the
+// end of the root, dropping repeated ones.
+func (o optimizer) gather(root *markup) {
+
+	var (
+		defs []*markup
+		walk func(m *markup)
+	)
+
+	walk = func(m *markup) {
+
+		children := m.children[:0]
+
+		for _, child := range m.children {
+			switch child.name {
+			case "defs":
+				walk(child)
+				defs = append(defs, child.children...)
+			case "mask":
+				defs = append(defs, child)
+			default:
+				walk(child)
+				children = append(children, child)
+			}
+		}
+
+		m.children = children
+	}
+
+	walk(root)
+
+	if len(defs) > 0 {
+		root.children = append(root.children, &markup{name: "defs", children: defs})
+	}
+}
+
+// shorten renames every id to a hash of the element it names, so equal
+// definitions share an id and different ones don't clash, even across
+// avatars on the same page. Repeated definitions are dropped.
+func (o optimizer) shorten(root *markup) {
+
+	var (
+		names = map[string]string{}
+		walk  func(m *markup, visit func(*markup))
+	)
+
+	walk = func(m *markup, visit func(*markup)) {
+
+		visit(m)
+
+		for _, child := range m.children {
+			walk(child, visit)
+		}
+	}
+
+	walk(root, func(m *markup) {
+
+		id, ok := m.attr("id")
+		if !ok {
+			return
+		}
+
+		content := strings.Builder{}
+		(&markup{name: m.name, attrs: slices.DeleteFunc(slices.Clone(m.attrs), func(a xml.Attr) bool { return a.Name.Local == "id" }), children: m.children}).write(&content, true)
+
+		h := fnv.New32a()
+		h.Write([]byte(content.String()))
+
+		names[id] = "i" + strconv.FormatUint(uint64(h.Sum32()), 36)
+	})
+
+	seen := map[string]bool{}
+
+	walk(root, func(m *markup) {
+
+		for i, a := range m.attrs {
+
+			switch name := qualified(a.Name); name {
+			case "id":
+				m.attrs[i].Value = names[a.Value]
+			case "href", "xlink:href":
+				if n, ok := names[strings.TrimPrefix(a.Value, "#")]; ok {
+					m.attrs[i].Value = "#" + n
+				}
+			case "aria-labelledby", "aria-describedby":
+
+				ids := strings.Fields(a.Value)
+
+				for j, id := range ids {
+					if n, ok := names[id]; ok {
+						ids[j] = n
+					}
+				}
+
+				m.attrs[i].Value = strings.Join(ids, " ")
+
+			default:
+				m.attrs[i].Value = referencePattern.ReplaceAllStringFunc(a.Value, func(ref string) string {
+					if n, ok := names[referencePattern.FindStringSubmatch(ref)[1]]; ok {
+						return "url(#" + n + ")"
+					}
+					return ref
+				})
+			}
+		}
+
+		if m.name != "defs" {
+			return
+		}
+
+		m.children = slices.DeleteFunc(m.children, func(d *markup) bool {
+
+			id, ok := d.attr("id")
+			if !ok {
+				return false
+			}
+
+			repeated := seen[names[id]]
+			seen[names[id]] = true
+
+			return repeated
+		})
+	})
+}
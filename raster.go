@@ -0,0 +1,1529 @@
+package goboringavatars
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The rasterizer draws the subset of SVG produced by this package, so avatars
+// can be turned into images without an external renderer. It understands
+// groups, nested svg elements, rect, circle, ellipse, line, polygon and path
+// elements, transforms, solid and linear gradient paint, masks, opacity,
+// gaussian blur, drop shadows and the overlay blend mode. Text, styles and
+// other elements are skipped.
+
+// node is an element of a parsed SVG document.
+type node struct {
+	name     string
+	attrs    map[string]string
+	children []*node
+}
+
+func (n *node) attr(name, fallback string) string {
+	if v, ok := n.attrs[name]; ok {
+		return v
+	}
+	return fallback
+}
+
+func (n *node) number(name string, fallback float64) float64 {
+
+	v, ok := n.attrs[name]
+	if !ok {
+		return fallback
+	}
+
+	f, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(v), "px"), 64)
+	if err != nil {
+		return fallback
+	}
+
+	return f
+}
+
+// parseSVG parses an SVG document into a tree of nodes.
+func parseSVG(svg string) (*node, error) {
+
+	var (
+		decoder = xml.NewDecoder(strings.NewReader(svg))
+		stack   []*node
+		root    *node
+	)
+
+	for {
+
+		token, err := decoder.Token()
+		if err != nil {
+			if root != nil && len(stack) == 0 {
+				return root, nil
+			}
+			return nil, fmt.Errorf("unable to parse svg: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+
+			n := &node{name: t.Name.Local, attrs: make(map[string]string, len(t.Attr))}
+
+			for _, a := range t.Attr {
+				n.attrs[a.Name.Local] = a.Value
+			}
+
+			// Declarations in the style attribute take precedence over attributes.
+			for _, declaration := range strings.Split(n.attrs["style"], ";") {
+				if property, value, ok := strings.Cut(declaration, ":"); ok {
+					n.attrs[strings.TrimSpace(property)] = strings.TrimSpace(value)
+				}
+			}
+
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, n)
+			} else if root == nil {
+				root = n
+			}
+
+			stack = append(stack, n)
+
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		}
+
+	}
+
+}
+
+// matrix is an affine transform: x' = a*x + c*y + e, y' = b*x + d*y + f.
+type matrix [6]float64
+
+var identity = matrix{1, 0, 0, 1, 0, 0}
+
+// mul returns the transform that applies n and then m.
+func (m matrix) mul(n matrix) matrix {
+	return matrix{
+		m[0]*n[0] + m[2]*n[1],
+		m[1]*n[0] + m[3]*n[1],
+		m[0]*n[2] + m[2]*n[3],
+		m[1]*n[2] + m[3]*n[3],
+		m[0]*n[4] + m[2]*n[5] + m[4],
+		m[1]*n[4] + m[3]*n[5] + m[5],
+	}
+}
+
+func (m matrix) apply(p point) point {
+	return point{m[0]*p.x + m[2]*p.y + m[4], m[1]*p.x + m[3]*p.y + m[5]}
+}
+
+// scale returns the average factor the transform scales lengths by.
+func (m matrix) scale() float64 {
+	return math.Sqrt(math.Abs(m[0]*m[3] - m[1]*m[2]))
+}
+
+func (m matrix) invert() matrix {
+
+	det := m[0]*m[3] - m[1]*m[2]
+	if det == 0 {
+		return identity
+	}
+
+	return matrix{
+		m[3] / det,
+		-m[1] / det,
+		-m[2] / det,
+		m[0] / det,
+		(m[2]*m[5] - m[3]*m[4]) / det,
+		(m[1]*m[4] - m[0]*m[5]) / det,
+	}
+}
+
+func translation(x, y float64) matrix {
+	return matrix{1, 0, 0, 1, x, y}
+}
+
+func scaling(x, y float64) matrix {
+	return matrix{x, 0, 0, y, 0, 0}
+}
+
+func rotation(degrees, cx, cy float64) matrix {
+	sin, cos := math.Sincos(degrees * math.Pi / 180)
+	return translation(cx, cy).mul(matrix{cos, sin, -sin, cos, 0, 0}).mul(translation(-cx, -cy))
+}
+
+// parseNumbers reads the numbers of an attribute such as a viewBox or points list.
+func parseNumbers(s string) []float64 {
+
+	var list []float64
+
+	for _, field := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' || r == '\n' }) {
+		if f, err := strconv.ParseFloat(field, 64); err == nil {
+			list = append(list, f)
+		}
+	}
+
+	return list
+}
+
+// parseTransform parses the value of a transform attribute.
+func parseTransform(s string) matrix {
+
+	m := identity
+
+	for {
+
+		name, rest, ok := strings.Cut(s, "(")
+		if !ok {
+			return m
+		}
+
+		args, rest, _ := strings.Cut(rest, ")")
+		v := parseNumbers(args)
+		s = rest
+
+		arg := func(i int, fallback float64) float64 {
+			if i < len(v) {
+				return v[i]
+			}
+			return fallback
+		}
+
+		switch strings.TrimSpace(strings.Trim(name, ", ")) {
+		case "translate":
+			m = m.mul(translation(arg(0, 0), arg(1, 0)))
+		case "scale":
+			m = m.mul(scaling(arg(0, 1), arg(1, arg(0, 1))))
+		case "rotate":
+			m = m.mul(rotation(arg(0, 0), arg(1, 0), arg(2, 0)))
+		case "matrix":
+			if len(v) == 6 {
+				m = m.mul(matrix{v[0], v[1], v[2], v[3], v[4], v[5]})
+			}
+		case "skewX":
+			m = m.mul(matrix{1, 0, math.Tan(arg(0, 0) * math.Pi / 180), 1, 0, 0})
+		case "skewY":
+			m = m.mul(matrix{1, math.Tan(arg(0, 0) * math.Pi / 180), 0, 1, 0, 0})
+		}
+
+	}
+
+}
+
+type point struct {
+	x, y float64
+}
+
+// subpath is a flattened run of points, in user space until transformed.
+type subpath struct {
+	points []point
+	closed bool
+}
+
+// shape collects flattened subpaths while a path is traced.
+type shape struct {
+	paths []subpath
+	// detail is the device scale, used to pick how finely curves are flattened.
+	detail float64
+}
+
+func (s *shape) moveTo(p point) {
+	s.paths = append(s.paths, subpath{points: []point{p}})
+}
+
+func (s *shape) lineTo(p point) {
+
+	if len(s.paths) == 0 {
+		s.moveTo(p)
+		return
+	}
+
+	last := &s.paths[len(s.paths)-1]
+
+	// Drawing after a close starts a new subpath where the closed one began.
+	if last.closed {
+		s.paths = append(s.paths, subpath{points: []point{last.points[0], p}})
+		return
+	}
+
+	last.points = append(last.points, p)
+}
+
+func (s *shape) close() {
+	if len(s.paths) > 0 {
+		s.paths[len(s.paths)-1].closed = true
+	}
+}
+
+func (s *shape) current() point {
+
+	if len(s.paths) == 0 {
+		return point{}
+	}
+
+	last := s.paths[len(s.paths)-1]
+
+	if last.closed {
+		return last.points[0]
+	}
+
+	return last.points[len(last.points)-1]
+}
+
+func (s *shape) segments(length float64) int {
+	return min(max(int(math.Ceil(math.Sqrt(length*s.detail)*1.5)), 4), 128)
+}
+
+func (s *shape) cubicTo(c1, c2, p point) {
+
+	var (
+		p0 = s.current()
+		n  = s.segments(math.Hypot(c1.x-p0.x, c1.y-p0.y) + math.Hypot(c2.x-c1.x, c2.y-c1.y) + math.Hypot(p.x-c2.x, p.y-c2.y))
+	)
+
+	for i := 1; i <= n; i++ {
+		t := float64(i) / float64(n)
+		u := 1 - t
+		s.lineTo(point{
+			u*u*u*p0.x + 3*u*u*t*c1.x + 3*u*t*t*c2.x + t*t*t*p.x,
+			u*u*u*p0.y + 3*u*u*t*c1.y + 3*u*t*t*c2.y + t*t*t*p.y,
+		})
+	}
+}
+
+func (s *shape) quadTo(c, p point) {
+
+	var (
+		p0 = s.current()
+		n  = s.segments(math.Hypot(c.x-p0.x, c.y-p0.y) + math.Hypot(p.x-c.x, p.y-c.y))
+	)
+
+	for i := 1; i <= n; i++ {
+		t := float64(i) / float64(n)
+		u := 1 - t
+		s.lineTo(point{u*u*p0.x + 2*u*t*c.x + t*t*p.x, u*u*p0.y + 2*u*t*c.y + t*t*p.y})
+	}
+}
+
+// arcTo draws an elliptical arc, converting from the endpoint parameters SVG
+// uses to a center and a pair of angles.
+func (s *shape) arcTo(rx, ry, degrees float64, large, sweep bool, p point) {
+
+	p0 := s.current()
+
+	rx, ry = math.Abs(rx), math.Abs(ry)
+
+	if rx == 0 || ry == 0 || p0 == p {
+		s.lineTo(p)
+		return
+	}
+
+	var (
+		sin, cos = math.Sincos(degrees * math.Pi / 180)
+		dx       = (p0.x - p.x) / 2
+		dy       = (p0.y - p.y) / 2
+		x1       = cos*dx + sin*dy
+		y1       = -sin*dx + cos*dy
+	)
+
+	if lambda := x1*x1/(rx*rx) + y1*y1/(ry*ry); lambda > 1 {
+		rx *= math.Sqrt(lambda)
+		ry *= math.Sqrt(lambda)
+	}
+
+	num := rx*rx*ry*ry - rx*rx*y1*y1 - ry*ry*x1*x1
+	den := rx*rx*y1*y1 + ry*ry*x1*x1
+	coef := math.Sqrt(math.Max(num/den, 0))
+
+	if large == sweep {
+		coef = -coef
+	}
+
+	var (
+		cx1    = coef * rx * y1 / ry
+		cy1    = -coef * ry * x1 / rx
+		cx     = cos*cx1 - sin*cy1 + (p0.x+p.x)/2
+		cy     = sin*cx1 + cos*cy1 + (p0.y+p.y)/2
+		start  = math.Atan2((y1-cy1)/ry, (x1-cx1)/rx)
+		end    = math.Atan2((-y1-cy1)/ry, (-x1-cx1)/rx)
+		delta  = end - start
+		radius = math.Max(rx, ry) * s.detail
+	)
+
+	if sweep && delta < 0 {
+		delta += 2 * math.Pi
+	} else if !sweep && delta > 0 {
+		delta -= 2 * math.Pi
+	}
+
+	step := math.Pi / 2
+	if radius > 0.1 {
+		step = math.Min(step, 2*math.Acos(1-0.1/radius))
+	}
+
+	n := max(int(math.Ceil(math.Abs(delta)/step)), 2)
+
+	for i := 1; i < n; i++ {
+		a := start + delta*float64(i)/float64(n)
+		x, y := rx*math.Cos(a), ry*math.Sin(a)
+		s.lineTo(point{cos*x - sin*y + cx, sin*x + cos*y + cy})
+	}
+
+	s.lineTo(p)
+}
+
+// ellipse adds a closed ellipse to the shape.
+func (s *shape) ellipse(cx, cy, rx, ry float64) {
+	s.moveTo(point{cx + rx, cy})
+	s.arcTo(rx, ry, 0, false, true, point{cx - rx, cy})
+	s.arcTo(rx, ry, 0, false, true, point{cx + rx, cy})
+	s.close()
+}
+
+// rect adds a closed, optionally rounded, rectangle to the shape.
+func (s *shape) rect(x, y, w, h, rx, ry float64) {
+
+	rx, ry = math.Min(rx, w/2), math.Min(ry, h/2)
+
+	if rx <= 0 || ry <= 0 {
+		s.moveTo(point{x, y})
+		s.lineTo(point{x + w, y})
+		s.lineTo(point{x + w, y + h})
+		s.lineTo(point{x, y + h})
+		s.close()
+		return
+	}
+
+	s.moveTo(point{x + rx, y})
+	s.lineTo(point{x + w - rx, y})
+	s.arcTo(rx, ry, 0, false, true, point{x + w, y + ry})
+	s.lineTo(point{x + w, y + h - ry})
+	s.arcTo(rx, ry, 0, false, true, point{x + w - rx, y + h})
+	s.lineTo(point{x + rx, y + h})
+	s.arcTo(rx, ry, 0, false, true, point{x, y + h - ry})
+	s.lineTo(point{x, y + ry})
+	s.arcTo(rx, ry, 0, false, true, point{x + rx, y})
+	s.close()
+}
+
+// pathScanner reads the commands and numbers of path data.
+type pathScanner struct {
+	d   string
+	pos int
+}
+
+func (p *pathScanner) skip() {
+	for p.pos < len(p.d) && strings.IndexByte(" \t\r\n,", p.d[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// command returns the next command letter, if that is what follows.
+func (p *pathScanner) command() (byte, bool) {
+
+	p.skip()
+
+	if p.pos < len(p.d) && strings.IndexByte("MmLlHhVvCcSsQqTtAaZz", p.d[p.pos]) >= 0 {
+		p.pos++
+		return p.d[p.pos-1], true
+	}
+
+	return 0, false
+}
+
+// more reports whether a number follows.
+func (p *pathScanner) more() bool {
+	p.skip()
+	return p.pos < len(p.d) && strings.IndexByte("+-.0123456789", p.d[p.pos]) >= 0
+}
+
+func (p *pathScanner) number() (float64, error) {
+
+	p.skip()
+
+	var (
+		start = p.pos
+		dot   = false
+		exp   = false
+	)
+
+	if p.pos < len(p.d) && (p.d[p.pos] == '+' || p.d[p.pos] == '-') {
+		p.pos++
+	}
+
+	for p.pos < len(p.d) {
+
+		c := p.d[p.pos]
+
+		switch {
+		case c >= '0' && c <= '9':
+		case c == '.' && !dot && !exp:
+			dot = true
+		case (c == 'e' || c == 'E') && !exp:
+			exp = true
+			if p.pos+1 < len(p.d) && (p.d[p.pos+1] == '+' || p.d[p.pos+1] == '-') {
+				p.pos++
+			}
+		default:
+			return strconv.ParseFloat(p.d[start:p.pos], 64)
+		}
+
+		p.pos++
+	}
+
+	return strconv.ParseFloat(p.d[start:p.pos], 64)
+}
+
+// flag reads an arc flag, which may be packed against the next number.
+func (p *pathScanner) flag() (bool, error) {
+
+	p.skip()
+
+	if p.pos < len(p.d) && (p.d[p.pos] == '0' || p.d[p.pos] == '1') {
+		p.pos++
+		return p.d[p.pos-1] == '1', nil
+	}
+
+	return false, errors.New("invalid arc flag")
+}
+
+// path traces SVG path data into the shape.
+func (s *shape) path(d string) error {
+
+	var (
+		scan    = pathScanner{d: d}
+		cmd     byte
+		start   point
+		control point // last control point, for smooth curves
+		prev    byte
+	)
+
+	for {
+
+		if c, ok := scan.command(); ok {
+			cmd = c
+		} else if !scan.more() {
+			return nil
+		} else if cmd == 0 {
+			return errors.New("path data must start with a command")
+		}
+
+		var (
+			args []float64
+			n    int
+		)
+
+		switch cmd | 0x20 {
+		case 'z':
+			s.close()
+			prev, cmd = 'z', 0
+			continue
+		case 'h', 'v':
+			n = 1
+		case 'm', 'l', 't':
+			n = 2
+		case 's', 'q':
+			n = 4
+		case 'c':
+			n = 6
+		case 'a':
+			n = 7
+		}
+
+		var flags [2]bool
+
+		for i := 0; i < n; i++ {
+
+			if cmd|0x20 == 'a' && (i == 3 || i == 4) {
+				f, err := scan.flag()
+				if err != nil {
+					return err
+				}
+				flags[i-3] = f
+				args = append(args, 0)
+				continue
+			}
+
+			v, err := scan.number()
+			if err != nil {
+				return fmt.Errorf("invalid path data: %w", err)
+			}
+
+			args = append(args, v)
+		}
+
+		var (
+			cur      = s.current()
+			relative = cmd >= 'a'
+			at       = func(x, y float64) point {
+				if relative {
+					return point{cur.x + x, cur.y + y}
+				}
+				return point{x, y}
+			}
+			reflected = cur
+		)
+
+		if strings.IndexByte("cCsSqQtT", prev) >= 0 {
+			reflected = point{2*cur.x - control.x, 2*cur.y - control.y}
+		}
+
+		switch cmd | 0x20 {
+		case 'm':
+			start = at(args[0], args[1])
+			s.moveTo(start)
+			// Coordinates after a move are treated as lines.
+			if relative {
+				cmd = 'l'
+			} else {
+				cmd = 'L'
+			}
+		case 'l':
+			s.lineTo(at(args[0], args[1]))
+		case 'h':
+			if relative {
+				s.lineTo(point{cur.x + args[0], cur.y})
+			} else {
+				s.lineTo(point{args[0], cur.y})
+			}
+		case 'v':
+			if relative {
+				s.lineTo(point{cur.x, cur.y + args[0]})
+			} else {
+				s.lineTo(point{cur.x, args[0]})
+			}
+		case 'c':
+			control = at(args[2], args[3])
+			s.cubicTo(at(args[0], args[1]), control, at(args[4], args[5]))
+		case 's':
+			if strings.IndexByte("cCsS", prev) < 0 {
+				reflected = cur
+			}
+			control = at(args[0], args[1])
+			s.cubicTo(reflected, control, at(args[2], args[3]))
+		case 'q':
+			control = at(args[0], args[1])
+			s.quadTo(control, at(args[2], args[3]))
+		case 't':
+			if strings.IndexByte("qQtT", prev) < 0 {
+				reflected = cur
+			}
+			control = reflected
+			s.quadTo(control, at(args[0], args[1]))
+		case 'a':
+			s.arcTo(args[0], args[1], args[2], flags[0], flags[1], at(args[5], args[6]))
+		}
+
+		prev = cmd
+
+	}
+
+}
+
+// transform moves every point of the shape into device space.
+func (s *shape) transform(m matrix) {
+	for i := range s.paths {
+		for j, p := range s.paths[i].points {
+			s.paths[i].points[j] = m.apply(p)
+		}
+	}
+}
+
+func (s *shape) bounds() (minimum, maximum point) {
+
+	minimum = point{math.Inf(1), math.Inf(1)}
+	maximum = point{math.Inf(-1), math.Inf(-1)}
+
+	for _, path := range s.paths {
+		for _, p := range path.points {
+			minimum = point{math.Min(minimum.x, p.x), math.Min(minimum.y, p.y)}
+			maximum = point{math.Max(maximum.x, p.x), math.Max(maximum.y, p.y)}
+		}
+	}
+
+	return minimum, maximum
+}
+
+// layer is a premultiplied RGBA canvas with float channels.
+type layer struct {
+	w, h int
+	pix  []float32
+}
+
+func newLayer(w, h int) *layer {
+	return &layer{w: w, h: h, pix: make([]float32, w*h*4)}
+}
+
+// coverage holds how much of each pixel in a region a shape covers.
+type coverage struct {
+	x, y, w, h int
+	a          []float32
+}
+
+func (c *coverage) add(x, y int, v float32) {
+	if x >= c.x && x < c.x+c.w && y >= c.y && y < c.y+c.h {
+		c.a[(y-c.y)*c.w+x-c.x] += v
+	}
+}
+
+// region returns an empty coverage for the device bounds, clipped to the canvas.
+func region(minimum, maximum point, pad float64, w, h int) *coverage {
+
+	var (
+		x0 = max(int(math.Floor(minimum.x-pad)), 0)
+		y0 = max(int(math.Floor(minimum.y-pad)), 0)
+		x1 = min(int(math.Ceil(maximum.x+pad)), w)
+		y1 = min(int(math.Ceil(maximum.y+pad)), h)
+	)
+
+	if x1 <= x0 || y1 <= y0 {
+		return &coverage{}
+	}
+
+	return &coverage{x: x0, y: y0, w: x1 - x0, h: y1 - y0, a: make([]float32, (x1-x0)*(y1-y0))}
+}
+
+// fill returns the coverage of the shape, in device space, with the nonzero rule.
+func (s *shape) fill(w, h int) *coverage {
+
+	const samples = 4
+
+	type edge struct {
+		x0, y0, x1, y1 float64
+		dir            int
+	}
+
+	var edges []edge
+
+	for _, path := range s.paths {
+
+		points := path.points
+
+		for i := range points {
+
+			a, b := points[i], points[(i+1)%len(points)]
+
+			switch {
+			case a.y < b.y:
+				edges = append(edges, edge{a.x, a.y, b.x, b.y, 1})
+			case a.y > b.y:
+				edges = append(edges, edge{b.x, b.y, a.x, a.y, -1})
+			}
+		}
+	}
+
+	minimum, maximum := s.bounds()
+	cov := region(minimum, maximum, 1, w, h)
+
+	type crossing struct {
+		x   float64
+		dir int
+	}
+
+	var crossings []crossing
+
+	for y := cov.y; y < cov.y+cov.h; y++ {
+		for sample := 0; sample < samples; sample++ {
+
+			sy := float64(y) + (float64(sample)+0.5)/samples
+
+			crossings = crossings[:0]
+
+			for _, e := range edges {
+				if sy >= e.y0 && sy < e.y1 {
+					crossings = append(crossings, crossing{e.x0 + (sy-e.y0)*(e.x1-e.x0)/(e.y1-e.y0), e.dir})
+				}
+			}
+
+			sort.Slice(crossings, func(i, j int) bool { return crossings[i].x < crossings[j].x })
+
+			winding := 0
+
+			for i, c := range crossings {
+
+				winding += c.dir
+
+				if winding == 0 || i+1 == len(crossings) {
+					continue
+				}
+
+				span(cov, y, c.x, crossings[i+1].x, 1.0/samples)
+			}
+		}
+	}
+
+	return cov
+}
+
+// span adds weight to the pixels of row y between x0 and x1, with partial
+// coverage for the pixels at either end.
+func span(cov *coverage, y int, x0, x1 float64, weight float32) {
+
+	x0 = math.Max(x0, float64(cov.x))
+	x1 = math.Min(x1, float64(cov.x+cov.w))
+
+	if x1 <= x0 {
+		return
+	}
+
+	i0, i1 := int(x0), int(x1)
+
+	if i0 == i1 {
+		cov.add(i0, y, weight*float32(x1-x0))
+		return
+	}
+
+	cov.add(i0, y, weight*float32(float64(i0+1)-x0))
+
+	for i := i0 + 1; i < i1; i++ {
+		cov.add(i, y, weight)
+	}
+
+	cov.add(i1, y, weight*float32(x1-float64(i1)))
+}
+
+// stroke returns the coverage of the outline of the shape, in device space,
+// for a line of the given width.
+func (s *shape) stroke(w, h int, width float64, butt bool) *coverage {
+
+	minimum, maximum := s.bounds()
+	cov := region(minimum, maximum, width/2+1, w, h)
+
+	type segment struct {
+		a, b        point
+		first, last bool
+	}
+
+	var segments []segment
+
+	for _, path := range s.paths {
+
+		points := path.points
+
+		if path.closed {
+			points = append(points[:len(points):len(points)], points[0])
+		}
+
+		for i := 0; i+1 < len(points); i++ {
+			segments = append(segments, segment{points[i], points[i+1], i == 0 && !path.closed, i+2 == len(points) && !path.closed})
+		}
+	}
+
+	half := width / 2
+
+	for y := cov.y; y < cov.y+cov.h; y++ {
+		for x := cov.x; x < cov.x+cov.w; x++ {
+
+			var (
+				p       = point{float64(x) + 0.5, float64(y) + 0.5}
+				nearest = math.Inf(1)
+			)
+
+			for _, seg := range segments {
+
+				var (
+					dx, dy = seg.b.x - seg.a.x, seg.b.y - seg.a.y
+					length = dx*dx + dy*dy
+					t      = 0.0
+				)
+
+				if length > 0 {
+					t = ((p.x-seg.a.x)*dx + (p.y-seg.a.y)*dy) / length
+				}
+
+				// Butt caps end the line flush with its end points.
+				if butt && ((seg.first && t < 0) || (seg.last && t > 1)) {
+					continue
+				}
+
+				t = math.Max(0, math.Min(1, t))
+
+				nearest = math.Min(nearest, math.Hypot(p.x-seg.a.x-t*dx, p.y-seg.a.y-t*dy))
+			}
+
+			if v := math.Min(half+0.5-nearest, 1); v > 0 {
+				cov.a[(y-cov.y)*cov.w+x-cov.x] = float32(math.Min(v, half*2))
+			}
+		}
+	}
+
+	return cov
+}
+
+// paint returns the premultiplied color at a device pixel.
+type paint func(x, y int) [4]float32
+
+// parseColor parses the colors used by this package into straight RGBA.
+func parseColor(s string) ([4]float32, bool) {
+
+	s = strings.ToLower(strings.TrimSpace(s))
+
+	switch s {
+	case "none", "transparent", "":
+		return [4]float32{}, false
+	case "white":
+		return [4]float32{1, 1, 1, 1}, true
+	case "black", "currentcolor":
+		return [4]float32{0, 0, 0, 1}, true
+	}
+
+	r, g, b, a, ok := parseCSSColor(s)
+	if !ok {
+		return [4]float32{}, false
+	}
+
+	return [4]float32{float32(r) / 255, float32(g) / 255, float32(b) / 255, float32(a) / 255}, true
+}
+
+// premultiply returns a color with its channels multiplied by its alpha and opacity.
+func premultiply(c [4]float32, opacity float32) [4]float32 {
+	a := c[3] * opacity
+	return [4]float32{c[0] * a, c[1] * a, c[2] * a, a}
+}
+
+// renderer draws a parsed document onto layers.
+type renderer struct {
+	w, h int
+	ids  map[string]*node
+}
+
+// inherited holds the presentation attributes children inherit.
+type inherited struct {
+	fill, stroke             string
+	strokeWidth              float64
+	fillOpacity, strokeAlpha float64
+	linecap                  string
+}
+
+func (r *renderer) index(n *node) {
+
+	if id, ok := n.attrs["id"]; ok {
+		r.ids[id] = n
+	}
+
+	for _, child := range n.children {
+		r.index(child)
+	}
+}
+
+// reference returns the element a url(#id) value points at.
+func (r *renderer) reference(v string) *node {
+
+	v = strings.TrimSpace(v)
+
+	if !strings.HasPrefix(v, "url(") {
+		return nil
+	}
+
+	id := strings.Trim(strings.TrimSuffix(strings.TrimPrefix(v, "url("), ")"), `"' `)
+
+	return r.ids[strings.TrimPrefix(id, "#")]
+}
+
+// rasterize draws an SVG document onto a square image of the given size.
+func rasterize(svg string, px int) (*image.RGBA, error) {
+
+	root, err := parseSVG(svg)
+	if err != nil {
+		return nil, err
+	}
+
+	if root.name != "svg" {
+		return nil, errors.New("unable to parse svg: root element is not svg")
+	}
+
+	r := renderer{w: px, h: px, ids: map[string]*node{}}
+	r.index(root)
+
+	dst := newLayer(px, px)
+
+	view := parseNumbers(root.attr("viewBox", ""))
+	if len(view) != 4 {
+		view = []float64{0, 0, root.number("width", float64(px)), root.number("height", float64(px))}
+	}
+
+	ctm := scaling(float64(px)/view[2], float64(px)/view[3]).mul(translation(-view[0], -view[1]))
+
+	r.children(dst, root, ctm, inherited{fill: "black", strokeWidth: 1, fillOpacity: 1, strokeAlpha: 1, linecap: "butt"}.with(root))
+
+	img := image.NewRGBA(image.Rect(0, 0, px, px))
+
+	for i, v := range dst.pix {
+		img.Pix[i] = uint8(math.Round(float64(min(max(v, 0), 1)) * 255))
+	}
+
+	return img, nil
+}
+
+// with returns the inherited attributes after applying those set on the node.
+func (in inherited) with(n *node) inherited {
+
+	if v, ok := n.attrs["fill"]; ok {
+		in.fill = v
+	}
+
+	if v, ok := n.attrs["stroke"]; ok {
+		in.stroke = v
+	}
+
+	in.strokeWidth = n.number("stroke-width", in.strokeWidth)
+	in.fillOpacity = n.number("fill-opacity", in.fillOpacity)
+	in.strokeAlpha = n.number("stroke-opacity", in.strokeAlpha)
+	in.linecap = n.attr("stroke-linecap", in.linecap)
+
+	return in
+}
+
+func (r *renderer) children(dst *layer, n *node, ctm matrix, in inherited) {
+	for _, child := range n.children {
+		r.node(dst, child, ctm, in)
+	}
+}
+
+// node draws an element and its children.
+func (r *renderer) node(dst *layer, n *node, ctm matrix, in inherited) {
+
+	switch n.name {
+	case "g", "svg", "rect", "circle", "ellipse", "line", "path", "polygon", "polyline":
+	default:
+		return
+	}
+
+	if n.attr("display", "") == "none" || n.attr("visibility", "") == "hidden" {
+		return
+	}
+
+	ctm = ctm.mul(parseTransform(n.attr("transform", "")))
+	in = in.with(n)
+
+	var (
+		opacity = n.number("opacity", 1)
+		mask    = r.reference(n.attr("mask", ""))
+		filter  = r.reference(n.attr("filter", ""))
+		blend   = n.attr("mix-blend-mode", "normal")
+		clip    *coverage
+		target  = dst
+	)
+
+	if n.name == "svg" {
+
+		var (
+			x, y = n.number("x", 0), n.number("y", 0)
+			w, h = n.number("width", 0), n.number("height", 0)
+			view = parseNumbers(n.attr("viewBox", ""))
+		)
+
+		viewport := shape{}
+		viewport.rect(x, y, w, h, 0, 0)
+		viewport.transform(ctm)
+		clip = viewport.fill(r.w, r.h)
+
+		ctm = ctm.mul(translation(x, y))
+
+		if len(view) == 4 && view[2] > 0 && view[3] > 0 {
+
+			// The viewBox keeps its aspect ratio, centred in the viewport, and
+			// either fits inside it or, with slice, covers it.
+			sx, sy := w/view[2], h/view[3]
+
+			switch fit := strings.Fields(n.attr("preserveAspectRatio", "")); {
+			case len(fit) > 0 && fit[0] == "none":
+			case len(fit) > 1 && fit[1] == "slice":
+				sx = max(sx, sy)
+				sy = sx
+			default:
+				sx = min(sx, sy)
+				sy = sx
+			}
+
+			ctm = ctm.mul(translation((w-view[2]*sx)/2, (h-view[3]*sy)/2)).mul(scaling(sx, sy)).mul(translation(-view[0], -view[1]))
+		}
+	}
+
+	isolated := mask != nil || filter != nil || clip != nil || blend != "normal" || (opacity < 1 && len(n.children) > 0)
+
+	if isolated {
+		target = newLayer(r.w, r.h)
+	}
+
+	switch n.name {
+	case "g", "svg":
+		r.children(target, n, ctm, in)
+	default:
+		r.shape(target, n, ctm, in, float32(opacity))
+	}
+
+	if !isolated {
+		return
+	}
+
+	if filter != nil {
+		r.filter(target, filter, ctm)
+	}
+
+	if mask != nil {
+		target.multiply(r.mask(mask, ctm))
+	}
+
+	if clip != nil {
+		target.multiply(clip)
+	}
+
+	if len(n.children) == 0 {
+		opacity = 1 // Shapes apply their opacity when painted.
+	}
+
+	dst.composite(target, float32(opacity), blend)
+}
+
+// geometry traces the outline of a shape element in user space.
+func (r *renderer) geometry(n *node, detail float64) (*shape, error) {
+
+	s := &shape{detail: detail}
+
+	switch n.name {
+	case "rect":
+
+		var (
+			_, hasRX = n.attrs["rx"]
+			_, hasRY = n.attrs["ry"]
+			radiusX  = n.number("rx", 0)
+			radiusY  = n.number("ry", 0)
+		)
+
+		// A missing radius takes the value of the other one.
+		switch {
+		case hasRX && !hasRY:
+			radiusY = radiusX
+		case hasRY && !hasRX:
+			radiusX = radiusY
+		}
+
+		s.rect(n.number("x", 0), n.number("y", 0), n.number("width", 0), n.number("height", 0), radiusX, radiusY)
+
+	case "circle":
+		s.ellipse(n.number("cx", 0), n.number("cy", 0), n.number("r", 0), n.number("r", 0))
+	case "ellipse":
+		s.ellipse(n.number("cx", 0), n.number("cy", 0), n.number("rx", 0), n.number("ry", 0))
+	case "line":
+		s.moveTo(point{n.number("x1", 0), n.number("y1", 0)})
+		s.lineTo(point{n.number("x2", 0), n.number("y2", 0)})
+	case "polygon", "polyline":
+
+		v := parseNumbers(n.attr("points", ""))
+
+		for i := 0; i+1 < len(v); i += 2 {
+			s.lineTo(point{v[i], v[i+1]})
+		}
+
+		if n.name == "polygon" {
+			s.close()
+		}
+
+	case "path":
+		if err := s.path(n.attr("d", "")); err != nil {
+			return s, err
+		}
+	}
+
+	return s, nil
+}
+
+// shape fills and strokes a shape element onto the layer.
+func (r *renderer) shape(dst *layer, n *node, ctm matrix, in inherited, opacity float32) {
+
+	s, err := r.geometry(n, ctm.scale())
+	if err != nil && len(s.paths) == 0 {
+		return
+	}
+
+	lo, hi := s.bounds()
+
+	s.transform(ctm)
+
+	if n.name != "line" && n.name != "polyline" {
+		if p := r.paint(in.fill, float32(in.fillOpacity)*opacity, ctm, lo, hi); p != nil {
+			dst.paint(s.fill(r.w, r.h), p)
+		}
+	}
+
+	if p := r.paint(in.stroke, float32(in.strokeAlpha)*opacity, ctm, lo, hi); p != nil && in.strokeWidth > 0 {
+		dst.paint(s.stroke(r.w, r.h, in.strokeWidth*ctm.scale(), in.linecap == "butt"), p)
+	}
+}
+
+// paint resolves a fill or stroke value. Gradients are laid out in the user
+// space of the element, or its bounding box when their units ask for it.
+func (r *renderer) paint(value string, opacity float32, ctm matrix, lo, hi point) paint {
+
+	if gradient := r.reference(value); gradient != nil {
+
+		if gradient.name != "linearGradient" {
+			return nil
+		}
+
+		var (
+			units = gradient.attr("gradientUnits", "objectBoundingBox")
+			coord = func(name string, fallback float64) float64 {
+				v := strings.TrimSpace(gradient.attr(name, ""))
+				if strings.HasSuffix(v, "%") {
+					f, err := strconv.ParseFloat(strings.TrimSuffix(v, "%"), 64)
+					if err == nil {
+						return f / 100
+					}
+				}
+				return gradient.number(name, fallback)
+			}
+			p1    = point{coord("x1", 0), coord("y1", 0)}
+			p2    = point{coord("x2", 1), coord("y2", 0)}
+			space = ctm.mul(parseTransform(gradient.attr("gradientTransform", "")))
+		)
+
+		if units == "objectBoundingBox" {
+			space = ctm.mul(translation(lo.x, lo.y)).mul(scaling(hi.x-lo.x, hi.y-lo.y)).mul(parseTransform(gradient.attr("gradientTransform", "")))
+		}
+
+		type stop struct {
+			offset float32
+			color  [4]float32
+		}
+
+		var stops []stop
+
+		for _, child := range gradient.children {
+
+			if child.name != "stop" {
+				continue
+			}
+
+			color, ok := parseColor(child.attr("stop-color", "black"))
+			if !ok {
+				color = [4]float32{}
+			}
+
+			color[3] *= float32(child.number("stop-opacity", 1))
+
+			offset := float32(child.number("offset", 0))
+			if v := child.attr("offset", ""); strings.HasSuffix(v, "%") {
+				f, _ := strconv.ParseFloat(strings.TrimSuffix(v, "%"), 64)
+				offset = float32(f / 100)
+			}
+
+			if len(stops) > 0 {
+				offset = max(offset, stops[len(stops)-1].offset)
+			}
+
+			stops = append(stops, stop{offset, color})
+		}
+
+		if len(stops) == 0 {
+			return nil
+		}
+
+		var (
+			inverse = space.invert()
+			dx, dy  = p2.x - p1.x, p2.y - p1.y
+			length  = dx*dx + dy*dy
+		)
+
+		return func(x, y int) [4]float32 {
+
+			p := inverse.apply(point{float64(x) + 0.5, float64(y) + 0.5})
+
+			t := float32(0)
+			if length > 0 {
+				t = float32(((p.x-p1.x)*dx + (p.y-p1.y)*dy) / length)
+			}
+
+			if t <= stops[0].offset {
+				return premultiply(stops[0].color, opacity)
+			}
+
+			for i := 1; i < len(stops); i++ {
+
+				if t > stops[i].offset {
+					continue
+				}
+
+				var (
+					a, b = stops[i-1], stops[i]
+					f    = (t - a.offset) / max(b.offset-a.offset, 1e-6)
+					c    [4]float32
+				)
+
+				for j := range c {
+					c[j] = a.color[j] + (b.color[j]-a.color[j])*f
+				}
+
+				return premultiply(c, opacity)
+			}
+
+			return premultiply(stops[len(stops)-1].color, opacity)
+		}
+	}
+
+	color, ok := parseColor(value)
+	if !ok {
+		return nil
+	}
+
+	c := premultiply(color, opacity)
+
+	return func(int, int) [4]float32 {
+		return c
+	}
+}
+
+// paint draws the paint onto the layer wherever the coverage is set.
+func (l *layer) paint(cov *coverage, p paint) {
+	for y := 0; y < cov.h; y++ {
+		for x := 0; x < cov.w; x++ {
+
+			a := min(cov.a[y*cov.w+x], 1)
+			if a <= 0 {
+				continue
+			}
+
+			var (
+				c = p(cov.x+x, cov.y+y)
+				i = ((cov.y+y)*l.w + cov.x + x) * 4
+				k = 1 - c[3]*a
+			)
+
+			for j := 0; j < 4; j++ {
+				l.pix[i+j] = c[j]*a + l.pix[i+j]*k
+			}
+		}
+	}
+}
+
+// multiply scales every pixel of the layer by the coverage, clearing
+// everything outside of it.
+func (l *layer) multiply(cov *coverage) {
+	for y := 0; y < l.h; y++ {
+		for x := 0; x < l.w; x++ {
+
+			var a float32
+
+			if x >= cov.x && x < cov.x+cov.w && y >= cov.y && y < cov.y+cov.h {
+				a = min(cov.a[(y-cov.y)*cov.w+x-cov.x], 1)
+			}
+
+			i := (y*l.w + x) * 4
+
+			for j := 0; j < 4; j++ {
+				l.pix[i+j] *= a
+			}
+		}
+	}
+}
+
+// composite draws src over the layer with the opacity and blend mode.
+func (l *layer) composite(src *layer, opacity float32, blend string) {
+	for i := 0; i < len(l.pix); i += 4 {
+
+		sa := src.pix[i+3] * opacity
+		if sa <= 0 {
+			continue
+		}
+
+		da := l.pix[i+3]
+
+		for j := 0; j < 3; j++ {
+
+			var (
+				s = src.pix[i+j] * opacity
+				d = l.pix[i+j]
+			)
+
+			if blend == "overlay" && da > 0 {
+
+				var (
+					cs  = s / sa
+					cb  = d / da
+					mix float32
+				)
+
+				if cb <= 0.5 {
+					mix = 2 * cs * cb
+				} else {
+					mix = 1 - 2*(1-cs)*(1-cb)
+				}
+
+				l.pix[i+j] = s*(1-da) + d*(1-sa) + sa*da*mix
+
+				continue
+			}
+
+			l.pix[i+j] = s + d*(1-sa)
+		}
+
+		l.pix[i+3] = sa + da*(1-sa)
+	}
+}
+
+// mask renders a mask element and returns its luminance, or its alpha when
+// the mask asks for it, as coverage.
+func (r *renderer) mask(n *node, ctm matrix) *coverage {
+
+	content := newLayer(r.w, r.h)
+
+	if n.attr("maskContentUnits", "userSpaceOnUse") == "userSpaceOnUse" {
+		r.children(content, n, ctm, inherited{fill: "black", strokeWidth: 1, fillOpacity: 1, strokeAlpha: 1, linecap: "butt"})
+	}
+
+	cov := &coverage{w: r.w, h: r.h, a: make([]float32, r.w*r.h)}
+	alpha := n.attr("mask-type", "luminance") == "alpha"
+
+	for i := range cov.a {
+
+		p := content.pix[i*4 : i*4+4]
+
+		if alpha {
+			cov.a[i] = p[3]
+		} else {
+			cov.a[i] = 0.2125*p[0] + 0.7154*p[1] + 0.0721*p[2]
+		}
+	}
+
+	return cov
+}
+
+// filter applies the primitives of a filter element to the layer. Only
+// gaussian blurs and drop shadows are drawn, other primitives are skipped.
+func (r *renderer) filter(l *layer, n *node, ctm matrix) {
+
+	scale := ctm.scale()
+
+	for _, primitive := range n.children {
+		switch primitive.name {
+		case "feGaussianBlur":
+			l.blur(primitive.number("stdDeviation", 0) * scale)
+		case "feDropShadow":
+
+			color, ok := parseColor(primitive.attr("flood-color", "black"))
+			if !ok {
+				continue
+			}
+
+			shadow := newLayer(l.w, l.h)
+			copy(shadow.pix, l.pix)
+			shadow.blur(primitive.number("stdDeviation", 2) * scale)
+
+			var (
+				c      = premultiply(color, float32(primitive.number("flood-opacity", 1)))
+				offset = point{primitive.number("dx", 2), primitive.number("dy", 2)}
+				dx     = int(math.Round(offset.x * ctm[0]))
+				dy     = int(math.Round(offset.y * ctm[3]))
+				under  = newLayer(l.w, l.h)
+			)
+
+			for y := 0; y < l.h; y++ {
+				for x := 0; x < l.w; x++ {
+
+					sx, sy := x-dx, y-dy
+					if sx < 0 || sy < 0 || sx >= l.w || sy >= l.h {
+						continue
+					}
+
+					a := shadow.pix[(sy*l.w+sx)*4+3]
+					i := (y*l.w + x) * 4
+
+					for j := 0; j < 4; j++ {
+						under.pix[i+j] = c[j] * a
+					}
+				}
+			}
+
+			under.composite(l, 1, "normal")
+			copy(l.pix, under.pix)
+		}
+	}
+}
+
+// blur approximates a gaussian blur with three box blurs in each direction.
+func (l *layer) blur(sigma float64) {
+
+	if sigma < 0.5 {
+		return
+	}
+
+	// Box sizes for three passes, from the W3C filter effects specification.
+	d := int(math.Floor(sigma*3*math.Sqrt(2*math.Pi)/4 + 0.5))
+
+	buffer := make([]float32, max(l.w, l.h)*4)
+
+	for pass := 0; pass < 3; pass++ {
+
+		behind, ahead := d/2, d/2
+
+		// An even box is split unevenly, alternating sides between the passes.
+		if d%2 == 0 {
+			switch pass {
+			case 0:
+				ahead--
+			case 1:
+				behind--
+			}
+		}
+
+		for y := 0; y < l.h; y++ {
+			l.boxBlur(buffer, y*l.w*4, 4, l.w, behind, ahead)
+		}
+
+		for x := 0; x < l.w; x++ {
+			l.boxBlur(buffer, x*4, l.w*4, l.h, behind, ahead)
+		}
+	}
+}
+
+// boxBlur averages a row or column of n pixels, starting at offset and
+// stride apart, over a window reaching behind and ahead of each pixel.
+func (l *layer) boxBlur(buffer []float32, offset, stride, n, behind, ahead int) {
+
+	var (
+		size = float32(behind + ahead + 1)
+		sum  [4]float32
+	)
+
+	at := func(i int) []float32 {
+		if i < 0 || i >= n {
+			return nil
+		}
+		return l.pix[offset+i*stride : offset+i*stride+4]
+	}
+
+	for i := -behind; i <= ahead; i++ {
+		if p := at(i); p != nil {
+			for j := range sum {
+				sum[j] += p[j]
+			}
+		}
+	}
+
+	for i := 0; i < n; i++ {
+
+		for j := range sum {
+			buffer[i*4+j] = sum[j] / size
+		}
+
+		if p := at(i + ahead + 1); p != nil {
+			for j := range sum {
+				sum[j] += p[j]
+			}
+		}
+
+		if p := at(i - behind); p != nil {
+			for j := range sum {
+				sum[j] -= p[j]
+			}
+		}
+	}
+
+	for i := 0; i < n; i++ {
+		copy(l.pix[offset+i*stride:offset+i*stride+4], buffer[i*4:i*4+4])
+	}
+}
@@ -10,9 +10,9 @@ const (
 	ringColors = 5
 )
 
-func generateRingColors(name string, colors []string) map[int]string {
+func generateRingColors(hash int, colors []string) map[int]string {
 
-	numFromName := hashCode(name)
+	numFromName := hash
 
 	shuffle := make(map[int]string)
 
@@ -36,7 +36,7 @@ func generateRingColors(name string, colors []string) map[int]string {
 
 func (c config) ring() string {
 
-	colors := generateRingColors(c.name, c.colors)
+	colors := generateRingColors(c.hash(), c.colors)
 
 	svg := strings.Builder{}
 
@@ -44,15 +44,32 @@ func (c config) ring() string {
 
 	svg.WriteString(fmt.Sprintf(`<path d="M0 0h90v45H0z" fill="%s"></path>`, colors[0]))
 	svg.WriteString(fmt.Sprintf(`<path d="M0 45h90v45H0z" fill="%s"></path>`, colors[1]))
-	svg.WriteString(fmt.Sprintf(`<path d="M83 45a38 38 0 00-76 0h76z" fill="%s"></path>`, colors[2]))
-	svg.WriteString(fmt.Sprintf(`<path d="M83 45a38 38 0 01-76 0h76z" fill="%s"></path>`, colors[3]))
-	svg.WriteString(fmt.Sprintf(`<path d="M77 45a32 32 0 10-64 0h64z" fill="%s"></path>`, colors[4]))
-	svg.WriteString(fmt.Sprintf(`<path d="M77 45a32 32 0 11-64 0h64z" fill="%s"></path>`, colors[5]))
-	svg.WriteString(fmt.Sprintf(`<path d="M71 45a26 26 0 00-52 0h52z" fill="%s"></path>`, colors[6]))
-	svg.WriteString(fmt.Sprintf(`<path d="M71 45a26 26 0 01-52 0h52z" fill="%s"></path>`, colors[7]))
-	svg.WriteString(fmt.Sprintf(`<circle cx="45" cy="45" r="23" fill="%s"></circle>`, colors[8]))
-
-	c.end(&svg)
+	var motions []motion
+
+	if c.animate {
+		motions = ringMotions(c.hash())
+	}
+
+	for i, band := range []string{
+		fmt.Sprintf(`<path d="M83 45a38 38 0 00-76 0h76z" fill="%s"></path><path d="M83 45a38 38 0 01-76 0h76z" fill="%s"></path>`, colors[2], colors[3]),
+		fmt.Sprintf(`<path d="M77 45a32 32 0 10-64 0h64z" fill="%s"></path><path d="M77 45a32 32 0 11-64 0h64z" fill="%s"></path>`, colors[4], colors[5]),
+		fmt.Sprintf(`<path d="M71 45a26 26 0 00-52 0h52z" fill="%s"></path><path d="M71 45a26 26 0 01-52 0h52z" fill="%s"></path>`, colors[6], colors[7]),
+		fmt.Sprintf(`<circle cx="45" cy="45" r="23" fill="%s"></circle>`, colors[8]),
+	} {
+
+		if c.animate {
+			c.animated(&svg, motions[i])
+		}
+
+		svg.WriteString(band)
+
+		if c.animate {
+			svg.WriteString(`</g>`)
+		}
+
+	}
+
+	c.end(&svg, ringSize, c.style(motions...))
 
 	return svg.String()
 
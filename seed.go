@@ -0,0 +1,67 @@
+package goboringavatars
+
+import (
+	"fmt"
+)
+
+// Seed draws another avatar for the same name, such as when a user rerolls
+// theirs. A seed gives the same avatar each time, so only the number needs to
+// be stored. Seed 0 is the avatar without a seed.
+func Seed(n int) Option {
+	return option(func(c *config) error {
+		c.seed = n
+		return nil
+	})
+}
+
+// hash returns the number the avatar is drawn from, the hash of the name
+// mixed with the seed.
+func (c config) hash() int {
+	return reseed(hashCode(c.name), c.seed)
+}
+
+// reseed mixes the seed into a hash, leaving it as it is for seed 0. The seed
+// is mixed into the number rather than the name, so no name draws the avatar
+// of another name's seed by spelling it out.
+func reseed(hash, seed int) int {
+
+	if seed == 0 {
+		return hash
+	}
+
+	h := uint32(hash) ^ uint32(seed)*0x9E3779B9
+
+	h ^= h >> 16
+	h *= 0x85EBCA6B
+	h ^= h >> 13
+	h *= 0xC2B2AE35
+	h ^= h >> 16
+
+	return int(h & 0x7FFFFFFF)
+}
+
+// Candidates generates the avatars of the first n seeds of the name, starting
+// with seed 0, to choose from. Their ids don't clash, so they can share a page.
+func Candidates(name string, n int, opts ...Option) ([]string, error) {
+
+	avatars := make([]string, 0, max(n, 0))
+
+	for seed := 0; seed < n; seed++ {
+
+		c, err := build(name, append(opts[:len(opts):len(opts)], Seed(seed))...)
+		if err != nil {
+			return nil, err
+		}
+
+		c.prefix = fmt.Sprintf("s%d-", seed)
+
+		svg, err := c.render()
+		if err != nil {
+			return nil, err
+		}
+
+		avatars = append(avatars, svg)
+	}
+
+	return avatars, nil
+}
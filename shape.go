@@ -0,0 +1,141 @@
+package goboringavatars
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// shapeBox is the size of the box the paths of the shapes are drawn in.
+const shapeBox = 100
+
+// Mask limits the shapes an Avatar can be cut to.
+type Mask struct {
+	mask   string
+	radius float64 // corner radius of a rounded square, as a percentage of the width
+	path   string  // path of a custom shape, in a 100 by 100 box
+}
+
+func (m Mask) String() string {
+	return m.mask
+}
+
+func ValidateMask(mask Mask) bool {
+	switch mask.String() {
+	case "", "squircle", "hexagon", "octagon", "shield":
+		return true
+	case "rounded":
+		return mask.radius >= 0 && mask.radius <= 50
+	case "custom":
+		return strings.TrimSpace(mask.path) != "" && (&shape{}).path(mask.path) == nil
+	default:
+		return false
+	}
+}
+
+var (
+	Circle   = Mask{} // Circle is the default Mask.
+	Squircle = Mask{mask: "squircle"}
+	Hexagon  = Mask{mask: "hexagon"}
+	Octagon  = Mask{mask: "octagon"}
+	Shield   = Mask{mask: "shield"}
+)
+
+// RoundedSquare is a square with corners rounded by the radius, as a
+// percentage of the width from 0, a square, to 50, a circle.
+func RoundedSquare(radius float64) Mask {
+	return Mask{mask: "rounded", radius: radius}
+}
+
+// CustomShape cuts the avatar to the SVG path data, drawn in a 100 by 100 box.
+func CustomShape(path string) Mask {
+	return Mask{mask: "custom", path: path}
+}
+
+// shapePaths are the paths of the built in shapes, in a 100 by 100 box.
+var shapePaths = map[Mask]string{
+	Squircle: "M50 0C10 0 0 10 0 50s10 50 50 50 50-10 50-50S90 0 50 0z",
+	Hexagon:  "M50 0l43.3 25v50L50 100 6.7 75V25z",
+	Octagon:  "M29.3 0h41.4L100 29.3v41.4L70.7 100H29.3L0 70.7V29.3z",
+	Shield:   "M50 0l50 12v38c0 28-22 45-50 50C22 95 0 78 0 50V12z",
+}
+
+// Shape cuts the Avatar to the shape of the Mask, instead of a circle.
+func Shape(mask Mask) Option {
+	return option(func(c *config) error {
+
+		if !ValidateMask(mask) {
+			return &OptionError{"Shape", mask, ErrInvalidShape}
+		}
+
+		c.shape = mask
+		c.square = false
+
+		return nil
+	})
+}
+
+// brush is how the outline of the avatar is filled and stroked.
+type brush struct {
+	fill   string
+	stroke string
+	width  float64
+}
+
+// attributes returns the brush as svg attributes, with the stroke width
+// divided by the scale of the element.
+func (p brush) attributes(scale float64) string {
+
+	s := fmt.Sprintf(`fill="%s"`, p.fill)
+
+	if p.stroke != "" {
+		s += fmt.Sprintf(` stroke="%s" stroke-width="%s"`, p.stroke, formatNumber(p.width/scale))
+	}
+
+	return s
+}
+
+// outline returns the element painting the shape of the avatar over a box of
+// size units at x, y.
+func (a config) outline(x, y, size float64, p brush) string {
+
+	s := strings.Builder{}
+
+	switch path := a.shape.path; {
+	case a.square, a.shape == Circle, a.shape.mask == "rounded":
+
+		s.WriteString(`<rect `)
+
+		if x != 0 || y != 0 {
+			s.WriteString(fmt.Sprintf(`x="%s" y="%s" `, formatNumber(x), formatNumber(y)))
+		}
+
+		s.WriteString(fmt.Sprintf(`width="%s" height="%s" `, formatNumber(size), formatNumber(size)))
+
+		switch {
+		case a.square:
+		case a.shape == Circle:
+			s.WriteString(fmt.Sprintf(`rx="%s" `, formatNumber(size*2)))
+		case a.shape.radius > 0:
+			s.WriteString(fmt.Sprintf(`rx="%s" `, formatNumber(size*a.shape.radius/100)))
+		}
+
+		s.WriteString(fmt.Sprintf(`%s></rect>`, p.attributes(1)))
+
+	default:
+
+		if path == "" {
+			path = shapePaths[a.shape]
+		}
+
+		transform := fmt.Sprintf("scale(%s)", formatNumber(size/shapeBox))
+
+		if x != 0 || y != 0 {
+			transform = fmt.Sprintf("translate(%s %s) %s", formatNumber(x), formatNumber(y), transform)
+		}
+
+		s.WriteString(fmt.Sprintf(`<path d="%s" transform="%s" %s></path>`, html.EscapeString(path), transform, p.attributes(size/shapeBox)))
+	}
+
+	return s.String()
+}
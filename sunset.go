@@ -2,7 +2,9 @@ package goboringavatars
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
+	"unicode"
 )
 
 const (
@@ -10,9 +12,9 @@ const (
 	sunsetSize     = 80
 )
 
-func genSunsetColors(name string, colors []string) map[int]string {
+func genSunsetColors(hash int, colors []string) map[int]string {
 
-	n := hashCode(name)
+	n := hash
 
 	list := make(map[int]string, sunsetElements)
 
@@ -26,19 +28,52 @@ func genSunsetColors(name string, colors []string) map[int]string {
 
 func (c config) sunset() string {
 
-	colors := genSunsetColors(c.name, c.colors)
+	colors := genSunsetColors(c.hash(), c.colors)
 
 	svg := strings.Builder{}
 
-	name := strings.ReplaceAll(c.name, " ", "")
+	// Only keep the characters that are safe in an id and a url() reference.
+	name := strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, c.name)
+
+	// Names that lost more than their spaces get the hash, so names such as
+	// "a.b" and "ab" don't share an id, and so do seeded ones.
+	if name != strings.ReplaceAll(c.name, " ", "") || c.seed != 0 {
+		name += "_" + strconv.Itoa(c.hash())
+	}
+
+	// Private avatars leave the name out entirely.
+	if c.private {
+		name = strconv.Itoa(c.hash())
+	}
+
+	paint0, paint1 := c.id("gradient_paint0_linear_"+name), c.id("gradient_paint1_linear_"+name)
 
 	c.start(&svg, "ring", sunsetSize)
 
-	svg.WriteString(fmt.Sprintf(`<path fill="url(#gradient_paint0_linear_%s)" d="M0 0h80v40H0z"></path><path fill="url(#gradient_paint1_linear_%s)" d="M0 40h80v40H0z"></path>`, name, name))
+	var motions []motion
+
+	if c.animate {
+
+		motions = sunsetMotions(c.hash())
+
+		// The bands overlap and run past the edge, so the drifting horizon never uncovers the background.
+		svg.WriteString(fmt.Sprintf(`<path fill="url(#%s)" d="M0 0h80v50H0z"></path>`, paint0))
+		c.animated(&svg, motions[0])
+		svg.WriteString(fmt.Sprintf(`<path fill="url(#%s)" d="M0 40h80v50H0z"></path></g>`, paint1))
+
+	} else {
+		svg.WriteString(fmt.Sprintf(`<path fill="url(#%s)" d="M0 0h80v40H0z"></path><path fill="url(#%s)" d="M0 40h80v40H0z"></path>`, paint0, paint1))
+	}
 
-	c.end(&svg,
-		fmt.Sprintf(`<linearGradient id="gradient_paint0_linear_%s" x1="%d" y1="0" x2="%d" y2="%d" gradientUnits="userSpaceOnUse"><stop stop-color="%s" /><stop offset="1" stop-color="%s" /></linearGradient>`, name, sunsetSize/2, sunsetSize/2, sunsetSize/2, colors[0], colors[1]),
-		fmt.Sprintf(`<linearGradient id="gradient_paint1_linear_%s" x1="%d" y1="%d" x2="%d" y2="%d" gradientUnits="userSpaceOnUse"><stop stop-color="%s" /><stop offset="1" stop-color="%s" /></linearGradient>`, name, sunsetSize/2, sunsetSize/2, sunsetSize/2, sunsetSize, colors[2], colors[3]),
+	c.end(&svg, sunsetSize,
+		c.style(motions...),
+		fmt.Sprintf(`<linearGradient id="%s" x1="%d" y1="0" x2="%d" y2="%d" gradientUnits="userSpaceOnUse"><stop stop-color="%s" /><stop offset="1" stop-color="%s" /></linearGradient>`, paint0, sunsetSize/2, sunsetSize/2, sunsetSize/2, colors[0], colors[1]),
+		fmt.Sprintf(`<linearGradient id="%s" x1="%d" y1="%d" x2="%d" y2="%d" gradientUnits="userSpaceOnUse"><stop stop-color="%s" /><stop offset="1" stop-color="%s" /></linearGradient>`, paint1, sunsetSize/2, sunsetSize/2, sunsetSize/2, sunsetSize, colors[2], colors[3]),
 	)
 
 	return svg.String()
@@ -0,0 +1,113 @@
+package goboringavatars
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// compress gzips the svg at a fixed level with an empty header, without a
+// file name or modification time, so the same svg always gives the same bytes.
+func compress(svg string) ([]byte, error) {
+
+	b := bytes.NewBuffer(nil)
+
+	z, err := gzip.NewWriterLevel(b, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := io.WriteString(z, svg); err != nil {
+		return nil, err
+	}
+
+	if err := z.Close(); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// SVGZ returns the Avatar compressed as an .svgz file.
+func (a Avatar) SVGZ() ([]byte, error) {
+
+	if a.err != nil {
+		return nil, a.err
+	}
+
+	return compress(a.raw)
+}
+
+// EncodeSVGZ writes the avatar for the name to w as an .svgz file. The
+// compression is deterministic, so the same input gives byte-identical output.
+func EncodeSVGZ(w io.Writer, name string, opts ...Option) error {
+
+	b, err := Render(name, opts...).SVGZ()
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(b)
+
+	return err
+}
+
+// Export writes the avatar of every name to dir twice, as file.svg and
+// file.svgz, where files maps each file name to the name its avatar is drawn
+// from. Keeping the file names apart from the names lets avatars be stored
+// under user ids. Nothing is written when a file name or an avatar is invalid.
+// Files are written in order of their names, and identical inputs give
+// byte-identical files.
+func Export(dir string, files map[string]string, opts ...Option) error {
+
+	keys := make([]string, 0, len(files))
+	for file := range files {
+		keys = append(keys, file)
+	}
+
+	slices.Sort(keys)
+
+	// Check every file and draw every avatar first, so a bad entry leaves nothing half written.
+	for _, file := range keys {
+		if file == "" || file != filepath.Base(file) || strings.ContainsAny(file, `/\`) || file == "." || file == ".." {
+			return fmt.Errorf("%w: %q", ErrInvalidFile, file)
+		}
+	}
+
+	avatars := make([]Avatar, 0, len(keys))
+
+	for _, file := range keys {
+
+		avatar := Render(files[file], opts...)
+		if avatar.err != nil {
+			return fmt.Errorf("%s: %w", file, avatar.err)
+		}
+
+		avatars = append(avatars, avatar)
+	}
+
+	for i, file := range keys {
+
+		compressed, err := avatars[i].SVGZ()
+		if err != nil {
+			return err
+		}
+
+		err = errors.Join(
+			os.WriteFile(filepath.Join(dir, file+".svg"), []byte(avatars[i].raw), 0o644),
+			os.WriteFile(filepath.Join(dir, file+".svgz"), compressed, 0o644),
+		)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
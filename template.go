@@ -0,0 +1,110 @@
+package goboringavatars
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+)
+
+// HTML returns the Avatar as trusted markup, so html/template writes it
+// inline instead of escaping it into text.
+func (a Avatar) HTML() template.HTML {
+
+	if a.err != nil {
+		return ""
+	}
+
+	// The markup is generated by this package, with the name and classes escaped.
+	return template.HTML(a.raw)
+}
+
+// URL returns the Avatar as a data URI that html/template accepts in src attributes.
+func (a Avatar) URL() template.URL {
+	return template.URL(a.DataURI())
+}
+
+// FuncMap returns template functions that render avatars in html/template.
+//
+// avatar writes the avatar inline, and avatarURL returns a data URI for the src
+// of an img element. Both take the name, followed by any of a variant name, a
+// size in pixels or an Option:
+//
+//	{{ avatar "Mary Baker" "beam" 64 }}
+//	<img src="{{ avatarURL "Mary Baker" "ring" }}" alt="">
+func FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"avatar": func(name string, args ...any) (template.HTML, error) {
+
+			opts, err := templateOptions(args)
+			if err != nil {
+				return "", err
+			}
+
+			a := Render(name, opts...)
+
+			return a.HTML(), a.err
+		},
+		"avatarURL": func(name string, args ...any) (template.URL, error) {
+
+			opts, err := templateOptions(args)
+			if err != nil {
+				return "", err
+			}
+
+			a := Render(name, opts...)
+
+			return a.URL(), a.err
+		},
+	}
+}
+
+// ParseName returns the variant with the given name, where "marble" and an
+// empty string are both the default.
+func ParseName(name string) (Name, error) {
+
+	name = strings.ToLower(strings.TrimSpace(name))
+
+	if name == "marble" {
+		return Marble, nil
+	}
+
+	if v := (Name{name}); ValidateName(v) {
+		return v, nil
+	}
+
+	return Name{}, fmt.Errorf("%w: %q", ErrInvalidVariant, name)
+}
+
+// templateOptions converts the arguments of the template functions into options.
+func templateOptions(args []any) ([]Option, error) {
+
+	opts := make([]Option, 0, len(args))
+
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case Option:
+			opts = append(opts, v)
+		case Name:
+			opts = append(opts, Variant(v))
+		case string:
+
+			variant, err := ParseName(v)
+			if err != nil {
+				return nil, err
+			}
+
+			opts = append(opts, Variant(variant))
+
+		case int:
+			opts = append(opts, Size(float64(v), "px"))
+		case int64:
+			opts = append(opts, Size(float64(v), "px"))
+		case float64:
+			opts = append(opts, Size(v, "px"))
+		default:
+			return nil, fmt.Errorf("unsupported avatar argument %v of type %T", arg, arg)
+		}
+	}
+
+	return opts, nil
+}
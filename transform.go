@@ -0,0 +1,166 @@
+package goboringavatars
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Padding shrinks the artwork of the Avatar by the percentage of its width on
+// each side, up to 50, showing the background around it.
+func Padding(percent float64) Option {
+	return option(func(c *config) error {
+
+		if !finite(percent) || percent < 0 || percent >= 50 {
+			return &OptionError{"Padding", percent, ErrInvalidTransform}
+		}
+
+		c.padding = percent
+
+		return nil
+	})
+}
+
+// Background fills the Avatar with the color behind the artwork, which shows
+// around it when it is padded, zoomed out or rotated.
+func Background(color string) Option {
+	return option(func(c *config) error {
+
+		if !validColor(color) {
+			return &OptionError{"Background", color, ErrInvalidColor}
+		}
+
+		c.background = color
+
+		return nil
+	})
+}
+
+// Rotate turns the artwork of the Avatar clockwise by the degrees, within its shape.
+func Rotate(degrees float64) Option {
+	return option(func(c *config) error {
+
+		if !finite(degrees) {
+			return &OptionError{"Rotate", degrees, ErrInvalidTransform}
+		}
+
+		c.rotate = degrees
+
+		return nil
+	})
+}
+
+// FlipHorizontal mirrors the artwork of the Avatar from left to right.
+func FlipHorizontal() Option {
+	return option(func(c *config) error {
+		c.flipX = true
+		return nil
+	})
+}
+
+// FlipVertical mirrors the artwork of the Avatar from top to bottom.
+func FlipVertical() Option {
+	return option(func(c *config) error {
+		c.flipY = true
+		return nil
+	})
+}
+
+// Zoom scales the artwork of the Avatar from its middle, cropping it when the
+// factor is above 1 and showing it smaller when below.
+func Zoom(factor float64) Option {
+	return option(func(c *config) error {
+
+		if !finite(factor) || factor <= 0 {
+			return &OptionError{"Zoom", factor, ErrInvalidTransform}
+		}
+
+		c.zoom = factor
+
+		return nil
+	})
+}
+
+// transform returns the transform that rotates, flips and zooms the artwork
+// of an svg of size units, or an empty string when it is unchanged.
+func (a config) transform(size int) string {
+
+	sx, sy := 1.0, 1.0
+
+	if a.zoom > 0 {
+		sx, sy = a.zoom, a.zoom
+	}
+
+	if a.flipX {
+		sx = -sx
+	}
+
+	if a.flipY {
+		sy = -sy
+	}
+
+	return around(size, a.rotate, sx, sy)
+}
+
+// around returns a transform rotating and scaling around the middle of an svg
+// of size units, or an empty string when it does neither.
+func around(size int, rotate, sx, sy float64) string {
+
+	if sx == 1 && sy == 1 && rotate == 0 {
+		return ""
+	}
+
+	var (
+		s      = strings.Builder{}
+		center = formatNumber(float64(size) / 2)
+	)
+
+	s.WriteString(fmt.Sprintf(`translate(%s %s)`, center, center))
+
+	if rotate != 0 {
+		s.WriteString(fmt.Sprintf(` rotate(%s)`, formatNumber(rotate)))
+	}
+
+	if sx != 1 || sy != 1 {
+		s.WriteString(fmt.Sprintf(` scale(%s %s)`, formatNumber(sx), formatNumber(sy)))
+	}
+
+	s.WriteString(fmt.Sprintf(` translate(-%s -%s)`, center, center))
+
+	return s.String()
+}
+
+// compose fills the background and opens the groups transforming the artwork.
+// Padded artwork is cut to the shape again, at its smaller size.
+func (a config) compose(svg *strings.Builder, maskID string, size int) {
+
+	if a.background != "" {
+		svg.WriteString(fmt.Sprintf(`<rect width="%d" height="%d" fill="%s"></rect>`, size, size, a.background))
+	}
+
+	if a.padding > 0 {
+		scale := 1 - a.padding/50
+		svg.WriteString(fmt.Sprintf(`<g transform="%s"><g mask="url(#%s)">`, around(size, 0, scale, scale), maskID))
+	}
+
+	if t := a.transform(size); t != "" {
+		svg.WriteString(fmt.Sprintf(`<g transform="%s">`, t))
+	}
+}
+
+// uncompose closes the groups opened by compose.
+func (a config) uncompose(svg *strings.Builder, size int) {
+
+	if a.transform(size) != "" {
+		svg.WriteString(`</g>`)
+	}
+
+	if a.padding > 0 {
+		svg.WriteString(`</g></g>`)
+	}
+}
+
+// finite reports whether the number is neither NaN nor infinite.
+func finite(n float64) bool {
+	return !math.IsNaN(n) && !math.IsInf(n, 0)
+}
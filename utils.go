@@ -1,11 +1,9 @@
 package goboringavatars
 
 import (
-	"errors"
 	"fmt"
+	"hash/fnv"
 	"math"
-	"strconv"
-	"strings"
 )
 
 // hashCode computes a hash code for a given string
@@ -26,6 +24,21 @@ func hashCode(name string) int {
 
 }
 
+// variantHash hashes the name with FNV-1a, apart from hashCode, for choices
+// that shouldn't follow the colors, and mixes in the seed.
+func variantHash(name string, seed int) uint32 {
+
+	h := fnv.New32a()
+	h.Write([]byte(name))
+
+	if seed == 0 {
+		return h.Sum32()
+	}
+
+	return h.Sum32() ^ uint32(reseed(0, seed))
+
+}
+
 // getDigit returns the nth digit of a number
 func getDigit(number, ntn int) int {
 	return (number / int(math.Pow(10, float64(ntn)))) % 10
@@ -51,31 +64,17 @@ func getRandomColor(number int, colors []string) string {
 	return colors[number%len(colors)]
 }
 
-// GetContrast determines the contrast color (black or white) for the given hex color.
-// It returns an error if the input is not a valid hex color.
-func getContrast(hexcolor string) (string, error) {
-	// Normalize the hex color string
-	hexcolor = strings.TrimPrefix(hexcolor, "#")
-	if len(hexcolor) != 6 {
-		return "", errors.New("invalid hex color format")
-	}
+// GetContrast determines the contrast color (black or white) for the given color.
+// It returns an error if the input is not a color parseCSSColor reads.
+func getContrast(color string) (string, error) {
 
-	// Parse the RGB components from the hex color
-	r, err := strconv.ParseInt(hexcolor[0:2], 16, 64)
-	if err != nil {
-		return "", fmt.Errorf("invalid red component: %w", err)
-	}
-	g, err := strconv.ParseInt(hexcolor[2:4], 16, 64)
-	if err != nil {
-		return "", fmt.Errorf("invalid green component: %w", err)
-	}
-	b, err := strconv.ParseInt(hexcolor[4:6], 16, 64)
-	if err != nil {
-		return "", fmt.Errorf("invalid blue component: %w", err)
+	r, g, b, _, ok := parseCSSColor(color)
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrInvalidColor, color)
 	}
 
 	// Calculate the YIQ luminance value
-	yiq := ((r * 299) + (g * 587) + (b * 114)) / 1000
+	yiq := ((int(r) * 299) + (int(g) * 587) + (int(b) * 114)) / 1000
 
 	// Determine the contrast color
 	if yiq >= 128 {